@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type LogLevel int
+
+const (
+	LogLevel_Off LogLevel = iota
+	LogLevel_Error
+	LogLevel_Info
+	LogLevel_Debug
+)
+
+var logLevelNames = []string{"off", "error", "info", "debug"}
+
+func (l LogLevel) String() string {
+	if l >= 0 && int(l) < len(logLevelNames) {
+		return logLevelNames[l]
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+func ParseLogLevel(s string) (LogLevel, error) {
+	for i, name := range logLevelNames {
+		if strings.EqualFold(s, name) {
+			return LogLevel(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, want one of %s", s, strings.Join(logLevelNames, ", "))
+}
+
+// MoveCheck tells what to do with the moves that break the game rules before
+// they are sent.
+type MoveCheck int
+
+const (
+	MoveCheck_Off MoveCheck = iota
+	MoveCheck_Reject
+	MoveCheck_Repair
+)
+
+var moveCheckNames = []string{"off", "reject", "repair"}
+
+func (c MoveCheck) String() string {
+	if c >= 0 && int(c) < len(moveCheckNames) {
+		return moveCheckNames[c]
+	}
+	return fmt.Sprintf("MoveCheck(%d)", int(c))
+}
+
+func ParseMoveCheck(s string) (MoveCheck, error) {
+	for i, name := range moveCheckNames {
+		if strings.EqualFold(s, name) {
+			return MoveCheck(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown move check %q, want one of %s", s, strings.Join(moveCheckNames, ", "))
+}
+
+// Config holds everything Start needs to know to play a game.
+type Config struct {
+	Host           string
+	Port           string
+	Token          string
+	ConnectTimeout time.Duration
+	LogLevel       LogLevel
+
+	// RecordFile is where the protocol stream is recorded, ReplayFile is a
+	// recording to replay instead of connecting to a server.
+	RecordFile string
+	ReplayFile string
+
+	// ProfileFile receives a CPU profile of the whole game.
+	ProfileFile string
+
+	// MoveCheck validates the moves of the strategy, see CheckMoves.
+	MoveCheck MoveCheck
+
+	// Recover turns a panic of the strategy into Action_None for the tick,
+	// see Recover. It is on by default.
+	Recover bool
+
+	// TimeLimit is the time the strategy may take over the whole game, 0 for
+	// none. See TimeBudget.
+	TimeLimit time.Duration
+
+	// TelemetryFile receives a JSON line per tick, see TickLogger; "-" is
+	// the standard error. Nothing is logged by default.
+	TelemetryFile string
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Host:           "127.0.0.1",
+		Port:           "31001",
+		Token:          "0000000000000000",
+		ConnectTimeout: 10 * time.Second,
+		LogLevel:       LogLevel_Error,
+		Recover:        true,
+	}
+}
+
+// configEnv maps flag names to the environment variables that set them.
+var configEnv = map[string]string{
+	"host":            "CODEWARS_HOST",
+	"port":            "CODEWARS_PORT",
+	"token":           "CODEWARS_TOKEN",
+	"connect-timeout": "CODEWARS_CONNECT_TIMEOUT",
+	"log-level":       "CODEWARS_LOG_LEVEL",
+	"record":          "CODEWARS_RECORD",
+	"replay":          "CODEWARS_REPLAY",
+	"profile":         "CODEWARS_PROFILE",
+	"check-moves":     "CODEWARS_CHECK_MOVES",
+	"recover":         "CODEWARS_RECOVER",
+	"time-limit":      "CODEWARS_TIME_LIMIT",
+	"telemetry":       "CODEWARS_TELEMETRY",
+}
+
+// ParseConfig builds the configuration from the command line arguments
+// (without the program name) and the environment. Flags override environment
+// variables, which override the defaults. The official runner passes host,
+// port and token as three positional arguments; they are accepted as well,
+// but conflict with the corresponding flags.
+func ParseConfig(args []string, getenv func(string) string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	var usage bytes.Buffer
+	fs := flag.NewFlagSet("codewars", flag.ContinueOnError)
+	fs.SetOutput(&usage)
+
+	fs.StringVar(&cfg.Host, "host", cfg.Host, "game server host")
+	fs.StringVar(&cfg.Port, "port", cfg.Port, "game server port")
+	fs.StringVar(&cfg.Token, "token", cfg.Token, "authentication token")
+	fs.DurationVar(&cfg.ConnectTimeout, "connect-timeout", cfg.ConnectTimeout, "timeout for connecting to the server, 0 for none")
+	logLevel := fs.String("log-level", cfg.LogLevel.String(), "log level: "+strings.Join(logLevelNames, ", "))
+	fs.StringVar(&cfg.RecordFile, "record", "", "record the protocol stream to `file`")
+	fs.StringVar(&cfg.ReplayFile, "replay", "", "replay a recorded `file` instead of connecting to a server")
+	fs.StringVar(&cfg.ProfileFile, "profile", "", "write a CPU profile to `file`")
+	moveCheck := fs.String("check-moves", cfg.MoveCheck.String(), "what to do with moves breaking the rules: "+strings.Join(moveCheckNames, ", "))
+	fs.BoolVar(&cfg.Recover, "recover", cfg.Recover, "lose only the tick, not the game, when the strategy panics")
+	fs.DurationVar(&cfg.TimeLimit, "time-limit", cfg.TimeLimit, "time the strategy may take over the whole game, 0 for none")
+	fs.StringVar(&cfg.TelemetryFile, "telemetry", "", "write a JSON line per tick to `file`, - for the standard error")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: codewars [flags] [host port token]\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nevery flag can also be set with its environment variable:\n")
+		fs.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(fs.Output(), "  %-16s %s\n", f.Name, configEnv[f.Name])
+		})
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if v := getenv(configEnv[f.Name]); v != "" && err == nil {
+			if serr := f.Value.Set(v); serr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %v", v, configEnv[f.Name], serr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil, fmt.Errorf("%w\n%s", err, usage.String())
+		}
+		return nil, fmt.Errorf("%v\n%s", err, usage.String())
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	switch positional := fs.Args(); len(positional) {
+	case 0:
+	case 3:
+		for _, name := range []string{"host", "port", "token"} {
+			if set[name] {
+				return nil, fmt.Errorf("%s is given both as -%s and as a positional argument", name, name)
+			}
+		}
+		cfg.Host, cfg.Port, cfg.Token = positional[0], positional[1], positional[2]
+	default:
+		return nil, fmt.Errorf("expected 0 or 3 positional arguments (host port token), got %d: %q", len(positional), positional)
+	}
+
+	if cfg.LogLevel, err = ParseLogLevel(*logLevel); err != nil {
+		return nil, err
+	}
+	if cfg.MoveCheck, err = ParseMoveCheck(*moveCheck); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (cfg *Config) validate() error {
+	if cfg.Host == "" {
+		return fmt.Errorf("empty host")
+	}
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %q", cfg.Port)
+	}
+	if cfg.Token == "" {
+		return fmt.Errorf("empty token")
+	}
+	if cfg.ConnectTimeout < 0 {
+		return fmt.Errorf("negative connect timeout %v", cfg.ConnectTimeout)
+	}
+	if cfg.TimeLimit < 0 {
+		return fmt.Errorf("negative time limit %v", cfg.TimeLimit)
+	}
+	if cfg.RecordFile != "" && cfg.ReplayFile != "" {
+		return fmt.Errorf("record and replay can't be used together")
+	}
+	return nil
+}
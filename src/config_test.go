@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+
+		// want changes the default configuration into the expected one.
+		want func(c *Config)
+		// err is part of the expected error, empty for none.
+		err string
+	}{
+		{name: "defaults", want: func(c *Config) {}},
+		{
+			name: "flags",
+			args: []string{"-host", "example.com", "-port=4000", "-log-level", "debug", "-check-moves=repair",
+				"-time-limit", "1m30s", "-recover=false", "-telemetry", "-"},
+			want: func(c *Config) {
+				c.Host, c.Port, c.LogLevel, c.MoveCheck = "example.com", "4000", LogLevel_Debug, MoveCheck_Repair
+				c.TimeLimit, c.Recover, c.TelemetryFile = 90*time.Second, false, "-"
+			},
+		},
+		{
+			name: "environment",
+			env: map[string]string{"CODEWARS_HOST": "example.com", "CODEWARS_CONNECT_TIMEOUT": "2s",
+				"CODEWARS_RECOVER": "false", "CODEWARS_REPLAY": "game.rec"},
+			want: func(c *Config) {
+				c.Host, c.ConnectTimeout, c.Recover, c.ReplayFile = "example.com", 2*time.Second, false, "game.rec"
+			},
+		},
+		{
+			name: "flags override the environment",
+			args: []string{"-host=flag.example.com", "-log-level=off"},
+			env:  map[string]string{"CODEWARS_HOST": "env.example.com", "CODEWARS_LOG_LEVEL": "info", "CODEWARS_PORT": "4000"},
+			want: func(c *Config) { c.Host, c.LogLevel, c.Port = "flag.example.com", LogLevel_Off, "4000" },
+		},
+		{
+			name: "positional arguments of the official runner",
+			args: []string{"example.com", "4000", "secret"},
+			want: func(c *Config) { c.Host, c.Port, c.Token = "example.com", "4000", "secret" },
+		},
+		{
+			name: "positional arguments override the environment",
+			args: []string{"-record", "game.rec", "example.com", "4000", "secret"},
+			env:  map[string]string{"CODEWARS_TOKEN": "env"},
+			want: func(c *Config) { c.Host, c.Port, c.Token, c.RecordFile = "example.com", "4000", "secret", "game.rec" },
+		},
+		{
+			name: "positional argument conflicting with its flag",
+			args: []string{"-port", "4000", "example.com", "4001", "secret"},
+			err:  "port is given both as -port and as a positional argument",
+		},
+		{
+			name: "wrong number of positional arguments",
+			args: []string{"example.com", "4000"},
+			err:  "expected 0 or 3 positional arguments",
+		},
+		{name: "malformed duration flag", args: []string{"-connect-timeout", "soon"}, err: "connect-timeout"},
+		{name: "malformed duration variable", env: map[string]string{"CODEWARS_TIME_LIMIT": "10"}, err: "CODEWARS_TIME_LIMIT"},
+		{name: "unknown flag", args: []string{"-verbose"}, err: "-verbose"},
+		{name: "unknown log level", args: []string{"-log-level", "loud"}, err: `unknown log level "loud"`},
+		{name: "unknown move check", env: map[string]string{"CODEWARS_CHECK_MOVES": "fix"}, err: `unknown move check "fix"`},
+		{name: "empty host", args: []string{"-host="}, err: "empty host"},
+		{name: "port out of range", args: []string{"-port", "70000"}, err: `invalid port "70000"`},
+		{name: "port not a number", env: map[string]string{"CODEWARS_PORT": "http"}, err: `invalid port "http"`},
+		{name: "empty token", args: []string{"-token="}, err: "empty token"},
+		{name: "negative connect timeout", args: []string{"-connect-timeout=-1s"}, err: "negative connect timeout"},
+		{name: "negative time limit", args: []string{"-time-limit=-1s"}, err: "negative time limit"},
+		{name: "record and replay", args: []string{"-record=a.rec", "-replay=b.rec"}, err: "record and replay"},
+	}
+
+	for _, tt := range tests {
+		cfg, err := ParseConfig(tt.args, func(key string) string { return tt.env[key] })
+
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		want := DefaultConfig()
+		tt.want(want)
+		if *cfg != *want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *cfg, *want)
+		}
+	}
+}
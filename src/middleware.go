@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	. "model"
+	"runtime/debug"
+	"time"
+)
+
+// StrategyFunc lets an ordinary function be used as a Strategy.
+type StrategyFunc func(p *Player, w *World, g *Game, m *Move)
+
+func (f StrategyFunc) Move(p *Player, w *World, g *Game, m *Move) {
+	f(p, w, g, m)
+}
+
+// Middleware wraps a strategy to add a feature around its Move, such as
+// timing or validation, without touching the strategy itself. The standard
+// middlewares are ContextStrategies and pass the context of the tick on.
+type Middleware func(next Strategy) Strategy
+
+// Chain wraps s with the middlewares, the first one outermost: it sees the
+// tick first and the move last. The standard middlewares work best in this
+// order:
+//
+//	Chain(s,
+//		LogTicks(...),     // logs every tick, the ones that panicked too
+//		Recover(...),      // catches panics from everything below
+//		CountActions(...), // counts the action of the move as it is sent
+//		CaptureMoves(...), // records the move as it is sent
+//		LogMoves(...),     // logs the move as it is sent
+//		Timing(...),       // times the strategy and the work it depends on
+//		CheckMoves(...),   // validates what the strategy produced
+//		Track(...),        // updates the trackers the strategy reads
+//	)
+func Chain(s Strategy, middlewares ...Middleware) Strategy {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		s = middlewares[i](s)
+	}
+	return s
+}
+
+// Recover turns a panic of the strategy into Action_None for the tick instead
+// of a crash. report, if set, receives the panic with its stack trace.
+func Recover(report func(w *World, err error)) Middleware {
+	return func(next Strategy) Strategy {
+		return ContextStrategyFunc(func(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+			defer func() {
+				if v := recover(); v != nil {
+					*m = *NewMove()
+					if report != nil {
+						report(w, fmt.Errorf("strategy panicked: %v\n%s", v, debug.Stack()))
+					}
+				}
+			}()
+			moveContext(ctx, next, p, w, g, m)
+		})
+	}
+}
+
+// CountActions keeps b up to date: it moves b to the tick before the strategy
+// runs and records the move it made. Strategies and helpers such as
+// tactics.Scheduler that read b then see every action sent, whoever built it.
+func CountActions(b *ActionBudget) Middleware {
+	return func(next Strategy) Strategy {
+		return ContextStrategyFunc(func(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+			b.Update(w)
+			moveContext(ctx, next, p, w, g, m)
+			b.Record(m)
+		})
+	}
+}
+
+// Timing reports how long every tick took.
+func Timing(report func(w *World, d time.Duration)) Middleware {
+	return func(next Strategy) Strategy {
+		return ContextStrategyFunc(func(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+			start := time.Now()
+			moveContext(ctx, next, p, w, g, m)
+			report(w, time.Since(start))
+		})
+	}
+}
+
+// Tracker is any state kept up to date once per tick, such as
+// model.EnemyMemory or model.GroupManager.
+type Tracker interface {
+	Update(w *World)
+}
+
+// Track updates the trackers, in order, before the strategy moves.
+func Track(trackers ...Tracker) Middleware {
+	return func(next Strategy) Strategy {
+		return ContextStrategyFunc(func(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+			for _, t := range trackers {
+				t.Update(w)
+			}
+			moveContext(ctx, next, p, w, g, m)
+		})
+	}
+}
+
+// LogMoves logs every move other than Action_None with logf, e.g. log.Printf.
+func LogMoves(logf func(format string, args ...interface{})) Middleware {
+	return func(next Strategy) Strategy {
+		return ContextStrategyFunc(func(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+			moveContext(ctx, next, p, w, g, m)
+			if m.Action != Action_None {
+				logf("tick %d: %+v", w.TickIndex, *m)
+			}
+		})
+	}
+}
+
+// CapturedMove is a move made on a tick.
+type CapturedMove struct {
+	TickIndex int
+	Move      Move
+}
+
+// CaptureMoves appends every move, Action_None included, to dst, e.g. to
+// compare two runs or feed a test. A tick on which the strategy panics is
+// skipped.
+func CaptureMoves(dst *[]CapturedMove) Middleware {
+	return func(next Strategy) Strategy {
+		return ContextStrategyFunc(func(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+			moveContext(ctx, next, p, w, g, m)
+			*dst = append(*dst, CapturedMove{TickIndex: w.TickIndex, Move: *m})
+		})
+	}
+}
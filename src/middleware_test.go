@@ -0,0 +1,84 @@
+package main
+
+import (
+	. "model"
+	"strings"
+	"testing"
+)
+
+func TestCountActions(t *testing.T) {
+	g := DefaultGame(1)
+	b := NewActionBudget(g)
+	me := &Player{Id: 1, Me: true}
+
+	// The strategy knows nothing about b: it selects on even ticks and
+	// panics on tick 4, which then sends no action.
+	s := Chain(StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {
+		if w.TickIndex == 4 {
+			panic("boom")
+		}
+		if w.TickIndex%2 == 0 {
+			*m = *SelectRect(0, 0, w.Width, w.Height)
+		}
+	}), Recover(nil), CountActions(b))
+
+	for tick := 0; tick < 6; tick++ {
+		s.Move(me, &World{TickIndex: tick, Players: []*Player{me}}, g, NewMove())
+	}
+
+	if b.Used() != 2 {
+		t.Errorf("%d actions counted, want 2", b.Used())
+	}
+	if got, want := b.Remaining(), g.BaseActionCount-2; got != want {
+		t.Errorf("%d actions remaining, want %d", got, want)
+	}
+}
+
+type trackerFunc func(w *World)
+
+func (f trackerFunc) Update(w *World) {
+	f(w)
+}
+
+func TestChain(t *testing.T) {
+	g := DefaultGame(1)
+	me := &Player{Id: 1, Me: true}
+
+	var calls []string
+	var captured []CapturedMove
+	s := Chain(StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {
+		calls = append(calls, "strategy")
+		*m = *MoveBy(10, 20, 0)
+		if w.TickIndex == 1 {
+			panic("boom")
+		}
+	}),
+		Recover(func(w *World, err error) { calls = append(calls, "recover") }),
+		CaptureMoves(&captured),
+		LogMoves(func(format string, args ...interface{}) { calls = append(calls, "log") }),
+		Track(trackerFunc(func(w *World) { calls = append(calls, "track") })),
+	)
+
+	for tick, want := range [][]string{
+		{"track", "strategy", "log"},
+		{"track", "strategy", "recover"},
+	} {
+		calls = nil
+		m := NewMove()
+		s.Move(me, &World{TickIndex: tick, Players: []*Player{me}}, g, m)
+
+		if strings.Join(calls, " ") != strings.Join(want, " ") {
+			t.Errorf("tick %d: calls %v, want %v", tick, calls, want)
+		}
+		if tick == 0 && *m != *MoveBy(10, 20, 0) {
+			t.Errorf("tick %d: move %+v, want the strategy's", tick, *m)
+		}
+		if tick == 1 && *m != *NewMove() {
+			t.Errorf("tick %d: move %+v after a panic, want Action_None", tick, *m)
+		}
+	}
+
+	if len(captured) != 1 || captured[0].TickIndex != 0 || captured[0].Move != *MoveBy(10, 20, 0) {
+		t.Errorf("captured %+v, want only the move of tick 0", captured)
+	}
+}
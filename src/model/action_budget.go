@@ -0,0 +1,149 @@
+package model
+
+// ActionBudget tracks the actions a strategy performs against the server
+// limit: BaseActionCount plus AdditionalActionCountPerControlCenter for every
+// owned control center during any ActionDetectionInterval consecutive ticks.
+//
+// Call Update at the start of every tick and Record with the move sent on
+// that tick. The CountActions middleware of the client does both around the
+// strategy, so that every move sent is counted however it was built.
+type ActionBudget struct {
+	game *Game
+
+	tick           int
+	controlCenters int
+
+	// cooldown is Player.RemainingActionCooldownTicks of the current tick,
+	// the server's own view of the budget.
+	cooldown int
+
+	// actions holds the ticks of the actions still inside the window, oldest
+	// first.
+	actions []int
+}
+
+func NewActionBudget(g *Game) *ActionBudget {
+	return &ActionBudget{game: g}
+}
+
+// Update moves the window to the tick of w and recounts the owned control
+// centers.
+func (b *ActionBudget) Update(w *World) {
+	b.tick = w.TickIndex
+	b.controlCenters = 0
+	b.cooldown = 0
+
+	if me := w.MyPlayer(); me != nil {
+		b.cooldown = me.RemainingActionCooldownTicks
+		for _, f := range w.Facilities {
+			if f.FacilityType == Facility_ControlCenter && f.OwnerPlayerId == me.Id {
+				b.controlCenters++
+			}
+		}
+	}
+
+	b.actions = b.inWindow(b.actions, b.tick)
+}
+
+// Record registers the move sent on the current tick and reports whether the
+// server accepts its action. Moves without an action and moves sent while the
+// budget is exhausted don't count. Only one move is sent per tick, so
+// recording the current tick again doesn't count twice.
+func (b *ActionBudget) Record(m *Move) bool {
+	if m == nil || m.Action == Action_None {
+		return false
+	}
+	if n := len(b.actions); n > 0 && b.actions[n-1] == b.tick {
+		return true
+	}
+	if !b.Available() {
+		return false
+	}
+	b.actions = append(b.actions, b.tick)
+	return true
+}
+
+// Limit returns the number of actions allowed per window.
+func (b *ActionBudget) Limit() int {
+	return b.game.BaseActionCount + b.game.AdditionalActionCountPerControlCenter*b.controlCenters
+}
+
+// Used returns the number of actions performed in the current window.
+func (b *ActionBudget) Used() int {
+	return len(b.actions)
+}
+
+// Remaining returns the number of actions that can still be performed in the
+// current window.
+func (b *ActionBudget) Remaining() int {
+	if b.cooldown > 0 {
+		return 0
+	}
+	if n := b.Limit() - b.Used(); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// Available reports whether an action can be performed on the current tick.
+func (b *ActionBudget) Available() bool {
+	return b.TicksUntilAvailable() == 0
+}
+
+// TicksUntilAvailable returns the number of ticks until the next action can be
+// performed, 0 if it can be performed right now.
+func (b *ActionBudget) TicksUntilAvailable() int {
+	wait := b.wait(b.actions, b.tick)
+	if b.cooldown > wait {
+		return b.cooldown
+	}
+	return wait
+}
+
+// TicksUntil returns the number of ticks until the n-th next action can be
+// performed if every action is performed as soon as the budget allows, at
+// most one per tick. TicksUntil(1) equals TicksUntilAvailable.
+func (b *ActionBudget) TicksUntil(n int) int {
+	if n <= 0 {
+		return 0
+	}
+
+	actions := append([]int(nil), b.actions...)
+	t := b.tick + b.TicksUntilAvailable()
+
+	for i := 1; ; i++ {
+		actions = b.inWindow(actions, t)
+		t += b.wait(actions, t)
+		if i == n {
+			return t - b.tick
+		}
+		actions = append(actions, t)
+		t++
+	}
+}
+
+// inWindow drops the actions performed ActionDetectionInterval or more ticks
+// before tick.
+func (b *ActionBudget) inWindow(actions []int, tick int) []int {
+	i := 0
+	for i < len(actions) && actions[i] <= tick-b.game.ActionDetectionInterval {
+		i++
+	}
+	return actions[i:]
+}
+
+// wait returns the number of ticks from tick until the window has room for
+// another action.
+func (b *ActionBudget) wait(actions []int, tick int) int {
+	limit := b.Limit()
+	if limit <= 0 {
+		return b.game.ActionDetectionInterval
+	}
+	if len(actions) < limit {
+		return 0
+	}
+	if w := actions[len(actions)-limit] + b.game.ActionDetectionInterval - tick; w > 0 {
+		return w
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package model
+
+import "testing"
+
+// budgetWorld returns the world of the tick in which player 1 owns the given
+// number of control centers. The enemy owns one too.
+func budgetWorld(tick, controlCenters int) *World {
+	w := &World{
+		TickIndex: tick,
+		Players:   []*Player{{Id: 1, Me: true}, {Id: 2}},
+		Facilities: []*Facility{
+			{Id: 100, FacilityType: Facility_ControlCenter, OwnerPlayerId: 2},
+			{Id: 101, FacilityType: Facility_VehicleFactory, OwnerPlayerId: 1},
+		},
+	}
+	for i := 0; i < controlCenters; i++ {
+		w.Facilities = append(w.Facilities, &Facility{Id: int64(i + 1), FacilityType: Facility_ControlCenter, OwnerPlayerId: 1})
+	}
+	return w
+}
+
+func TestActionBudgetWindow(t *testing.T) {
+	g := DefaultGame(1)
+	b := NewActionBudget(g)
+	move := &Move{Action: Action_Move}
+	limit, interval := g.BaseActionCount, g.ActionDetectionInterval
+
+	// Spend the whole budget on the first ticks.
+	for tick := 0; tick < limit; tick++ {
+		b.Update(budgetWorld(tick, 0))
+		if got, want := b.Remaining(), limit-tick; got != want {
+			t.Fatalf("tick %d: %d actions remaining, want %d", tick, got, want)
+		}
+		if !b.Record(move) {
+			t.Fatalf("tick %d: action refused with %d remaining", tick, b.Remaining())
+		}
+	}
+
+	b.Update(budgetWorld(limit, 0))
+	if b.Available() || b.Remaining() != 0 || b.Record(move) {
+		t.Fatalf("budget still available after %d actions", limit)
+	}
+	if got, want := b.TicksUntilAvailable(), interval-limit; got != want {
+		t.Errorf("TicksUntilAvailable() = %d, want %d", got, want)
+	}
+	if got, want := b.TicksUntil(3), interval-limit+2; got != want {
+		t.Errorf("TicksUntil(3) = %d, want %d", got, want)
+	}
+
+	// The first action leaves the window ActionDetectionInterval ticks later.
+	b.Update(budgetWorld(interval-1, 0))
+	if b.Available() {
+		t.Errorf("tick %d: available before the first action left the window", interval-1)
+	}
+	b.Update(budgetWorld(interval, 0))
+	if !b.Available() || b.Remaining() != 1 || b.Used() != limit-1 {
+		t.Errorf("tick %d: available %v with %d remaining and %d used, want 1 and %d",
+			interval, b.Available(), b.Remaining(), b.Used(), limit-1)
+	}
+}
+
+func TestActionBudgetRecord(t *testing.T) {
+	g := DefaultGame(1)
+	b := NewActionBudget(g)
+	b.Update(budgetWorld(0, 0))
+
+	if b.Record(nil) || b.Record(NewMove()) || b.Used() != 0 {
+		t.Errorf("a move without action counted: %d used", b.Used())
+	}
+
+	// Recording the move of the tick twice counts it once.
+	if !b.Record(&Move{Action: Action_Move}) || !b.Record(&Move{Action: Action_Move}) || b.Used() != 1 {
+		t.Errorf("%d actions used after recording one tick twice, want 1", b.Used())
+	}
+
+	// The server's own cooldown takes precedence.
+	w := budgetWorld(1, 0)
+	w.Players[0].RemainingActionCooldownTicks = 5
+	b.Update(w)
+	if b.Remaining() != 0 || b.TicksUntilAvailable() != 5 || b.Record(&Move{Action: Action_Move}) {
+		t.Errorf("cooldown ignored: %d remaining, available in %d ticks", b.Remaining(), b.TicksUntilAvailable())
+	}
+}
+
+func TestActionBudgetControlCenters(t *testing.T) {
+	g := DefaultGame(1)
+	b := NewActionBudget(g)
+
+	for _, n := range []int{0, 1, 3} {
+		b.Update(budgetWorld(0, n))
+		want := g.BaseActionCount + n*g.AdditionalActionCountPerControlCenter
+		if b.Limit() != want || b.Remaining() != want {
+			t.Errorf("%d control centers: limit %d with %d remaining, want %d", n, b.Limit(), b.Remaining(), want)
+		}
+	}
+
+	// Losing a control center shrinks the window at once.
+	for tick := 0; tick < g.BaseActionCount+g.AdditionalActionCountPerControlCenter; tick++ {
+		b.Update(budgetWorld(tick, 1))
+		b.Record(&Move{Action: Action_Move})
+	}
+	b.Update(budgetWorld(20, 0))
+	if b.Available() {
+		t.Errorf("available with %d actions used and a limit of %d", b.Used(), b.Limit())
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "fmt"
+
 /**
  * Возможные действия игрока.
  * <p>
@@ -81,3 +83,18 @@ const (
 	 */
 	Action_TacticalNuclearStrike
 )
+
+var actionNames = []string{
+	"none", "clear and select", "add to selection", "deselect", "assign", "dismiss", "disband",
+	"move", "rotate", "scale", "setup vehicle production", "tactical nuclear strike",
+}
+
+/**
+ * Название действия.
+ */
+func (a ActionType) String() string {
+	if int(a) < len(actionNames) {
+		return actionNames[a]
+	}
+	return fmt.Sprintf("ActionType(%d)", byte(a))
+}
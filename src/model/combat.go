@@ -0,0 +1,115 @@
+package model
+
+import "math"
+
+// The functions below follow the combat rules of the game: a vehicle deals
+// its ground or aerial damage, depending on whether the target flies, minus
+// the matching defence of the target, to a single target within the matching
+// attack range, and then waits AttackCooldownTicks ticks before the next
+// attack.
+
+// Damage returns the durability the defender loses from one attack of the
+// attacker, ignoring the distance between them.
+func Damage(attacker, defender *Vehicle) int {
+	dmg := attacker.GroundDamage - defender.GroundDefence
+	if defender.Aerial {
+		dmg = attacker.AerialDamage - defender.AerialDefence
+	}
+	if dmg < 0 {
+		return 0
+	}
+	return dmg
+}
+
+// TypeDamage is Damage for vehicles of the given types built from the game
+// constants.
+func TypeDamage(g *Game, attacker, defender VehicleType) int {
+	return Damage(g.NewVehicle(attacker), g.NewVehicle(defender))
+}
+
+// InAttackRange reports whether the defender is within the attack range the
+// attacker uses against it.
+func InAttackRange(attacker, defender *Vehicle) bool {
+	rangeSq := attacker.SquaredGroundAttackRange
+	if defender.Aerial {
+		rangeSq = attacker.SquaredAerialAttackRange
+	}
+	return attacker.GetSquaredDistanceUnit(&defender.Unit) <= rangeSq
+}
+
+// CanAttack reports whether the attacker belongs to another player, is in
+// range and would damage the defender. The cooldown is not checked.
+func CanAttack(attacker, defender *Vehicle) bool {
+	return attacker.PlayerId != defender.PlayerId && Damage(attacker, defender) > 0 && InAttackRange(attacker, defender)
+}
+
+// DamagePerTick returns the average damage the attacker deals to the
+// defender per tick of sustained fire.
+func DamagePerTick(attacker, defender *Vehicle) float64 {
+	return float64(Damage(attacker, defender)) / float64(cooldown(attacker))
+}
+
+// TypeDamagePerTick is DamagePerTick for vehicles of the given types built
+// from the game constants.
+func TypeDamagePerTick(g *Game, attacker, defender VehicleType) float64 {
+	return DamagePerTick(g.NewVehicle(attacker), g.NewVehicle(defender))
+}
+
+// AttacksWithin returns how many times the vehicle can attack during the next
+// ticks ticks, given its remaining cooldown.
+func AttacksWithin(v *Vehicle, ticks int) int {
+	if ticks <= v.RemainingAttackCooldownTicks {
+		return 0
+	}
+	return 1 + (ticks-1-v.RemainingAttackCooldownTicks)/cooldown(v)
+}
+
+// DamageWithin returns the damage the attacker deals to the defender during
+// the next ticks ticks if it keeps attacking it.
+func DamageWithin(attacker, defender *Vehicle, ticks int) int {
+	return AttacksWithin(attacker, ticks) * Damage(attacker, defender)
+}
+
+func cooldown(v *Vehicle) int {
+	if v.AttackCooldownTicks < 1 {
+		return 1
+	}
+	return v.AttackCooldownTicks
+}
+
+// GroupDamagePerTick returns the damage per tick a group of attackers deals
+// to a group of defenders when every attacker fires at the defender it
+// damages the most, as vehicles do in the game. Ranges are ignored, the
+// groups are assumed to be engaged.
+func GroupDamagePerTick(attackers, defenders []*Vehicle) float64 {
+	total := 0.0
+	for _, a := range attackers {
+		best := 0.0
+		for _, d := range defenders {
+			if dpt := DamagePerTick(a, d); dpt > best {
+				best = dpt
+			}
+		}
+		total += best
+	}
+	return total
+}
+
+// TimeToKill estimates the number of ticks the attackers need to destroy all
+// the defenders, +Inf if they can't damage them. Losses of the attackers and
+// overkill are not taken into account.
+func TimeToKill(attackers, defenders []*Vehicle) float64 {
+	durability := 0
+	for _, d := range defenders {
+		durability += d.Durability
+	}
+	if durability == 0 {
+		return 0
+	}
+
+	dpt := GroupDamagePerTick(attackers, defenders)
+	if dpt == 0 {
+		return math.Inf(1)
+	}
+	return float64(durability) / dpt
+}
@@ -0,0 +1,168 @@
+package model_test
+
+import (
+	"math"
+	. "model"
+	"testing"
+)
+
+func TestDamage(t *testing.T) {
+	g := DefaultGame(1)
+
+	tests := []struct {
+		attacker, defender VehicleType
+		want               int
+	}{
+		// Ground defenders take ground damage minus ground defence.
+		{Vehicle_Tank, Vehicle_Tank, 100 - 80},
+		{Vehicle_Tank, Vehicle_Ifv, 100 - 60},
+		{Vehicle_Helicopter, Vehicle_Arrv, 100 - 50},
+		{Vehicle_Ifv, Vehicle_Tank, 90 - 80},
+
+		// Aerial defenders take aerial damage minus aerial defence.
+		{Vehicle_Tank, Vehicle_Helicopter, 60 - 40},
+		{Vehicle_Ifv, Vehicle_Fighter, 80 - 70},
+		{Vehicle_Fighter, Vehicle_Helicopter, 100 - 40},
+		{Vehicle_Helicopter, Vehicle_Fighter, 80 - 70},
+
+		// More defence than damage is clamped to 0.
+		{Vehicle_Tank, Vehicle_Fighter, 0},
+		{Vehicle_Fighter, Vehicle_Tank, 0},
+		{Vehicle_Fighter, Vehicle_Arrv, 0},
+		{Vehicle_Arrv, Vehicle_Ifv, 0},
+		{Vehicle_Arrv, Vehicle_Helicopter, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Damage(g.NewVehicle(tt.attacker), g.NewVehicle(tt.defender)); got != tt.want {
+			t.Errorf("Damage(%v, %v) = %d, want %d", tt.attacker, tt.defender, got, tt.want)
+		}
+		if got := TypeDamage(g, tt.attacker, tt.defender); got != tt.want {
+			t.Errorf("TypeDamage(%v, %v) = %d, want %d", tt.attacker, tt.defender, got, tt.want)
+		}
+	}
+}
+
+func TestInAttackRange(t *testing.T) {
+	g := DefaultGame(1)
+
+	tests := []struct {
+		attacker, defender VehicleType
+		distance           float64
+		want               bool
+	}{
+		{Vehicle_Tank, Vehicle_Ifv, 0, true},
+		{Vehicle_Tank, Vehicle_Ifv, 20, true},
+		{Vehicle_Tank, Vehicle_Ifv, 20.01, false},
+		{Vehicle_Tank, Vehicle_Helicopter, 18, true},
+		{Vehicle_Tank, Vehicle_Helicopter, 19, false},
+		{Vehicle_Ifv, Vehicle_Tank, 19, false},
+		{Vehicle_Ifv, Vehicle_Fighter, 19, true},
+		{Vehicle_Arrv, Vehicle_Tank, 0, true},
+		{Vehicle_Arrv, Vehicle_Tank, 1, false},
+	}
+
+	for _, tt := range tests {
+		a, d := g.NewVehicle(tt.attacker), g.NewVehicle(tt.defender)
+		a.X, a.Y = 100, 100
+		d.X, d.Y = 100+tt.distance, 100
+		if got := InAttackRange(a, d); got != tt.want {
+			t.Errorf("InAttackRange(%v, %v) at %v = %v, want %v", tt.attacker, tt.defender, tt.distance, got, tt.want)
+		}
+	}
+}
+
+func TestAttacksWithin(t *testing.T) {
+	g := DefaultGame(1)
+
+	tests := []struct {
+		vehicle   VehicleType
+		remaining int
+		ticks     int
+		want      int
+	}{
+		// Cooldown 0: the first attack is on the first tick, the next
+		// one AttackCooldownTicks later.
+		{Vehicle_Tank, 0, 0, 0},
+		{Vehicle_Tank, 0, 1, 1},
+		{Vehicle_Tank, 0, 60, 1},
+		{Vehicle_Tank, 0, 61, 2},
+		{Vehicle_Tank, 0, 181, 4},
+
+		// No attack until the remaining cooldown has run out.
+		{Vehicle_Tank, 10, 9, 0},
+		{Vehicle_Tank, 10, 10, 0},
+		{Vehicle_Tank, 10, 11, 1},
+		{Vehicle_Tank, 10, 70, 1},
+		{Vehicle_Tank, 10, 71, 2},
+
+		// Without AttackCooldownTicks a vehicle counts as attacking every
+		// tick.
+		{Vehicle_Arrv, 0, 5, 5},
+	}
+
+	for _, tt := range tests {
+		v := g.NewVehicle(tt.vehicle)
+		v.RemainingAttackCooldownTicks = tt.remaining
+		if got := AttacksWithin(v, tt.ticks); got != tt.want {
+			t.Errorf("AttacksWithin(%v with cooldown %d, %d) = %d, want %d", tt.vehicle, tt.remaining, tt.ticks, got, tt.want)
+		}
+	}
+
+	tank, ifv := g.NewVehicle(Vehicle_Tank), g.NewVehicle(Vehicle_Ifv)
+	if got, want := DamageWithin(tank, ifv, 61), 2*(100-60); got != want {
+		t.Errorf("DamageWithin(tank, ifv, 61) = %d, want %d", got, want)
+	}
+}
+
+func vehicles(g *Game, types ...VehicleType) []*Vehicle {
+	var result []*Vehicle
+	for _, t := range types {
+		result = append(result, g.NewVehicle(t))
+	}
+	return result
+}
+
+func TestGroupDamagePerTick(t *testing.T) {
+	g := DefaultGame(1)
+
+	tests := []struct {
+		name                string
+		attackers, defender []VehicleType
+		want                float64
+	}{
+		{"every tank fires at the ifv", []VehicleType{Vehicle_Tank, Vehicle_Tank}, []VehicleType{Vehicle_Tank, Vehicle_Ifv}, 2 * 40.0 / 60},
+		{"fighters ignore ground", []VehicleType{Vehicle_Fighter}, []VehicleType{Vehicle_Tank, Vehicle_Helicopter}, 60.0 / 60},
+		{"nothing to hit", []VehicleType{Vehicle_Fighter, Vehicle_Arrv}, []VehicleType{Vehicle_Tank}, 0},
+		{"no defenders", []VehicleType{Vehicle_Tank}, nil, 0},
+	}
+
+	for _, tt := range tests {
+		if got := GroupDamagePerTick(vehicles(g, tt.attackers...), vehicles(g, tt.defender...)); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: GroupDamagePerTick = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTimeToKill(t *testing.T) {
+	g := DefaultGame(1)
+
+	tests := []struct {
+		name                string
+		attackers, defender []VehicleType
+		want                float64
+	}{
+		{"two tanks on a tank", []VehicleType{Vehicle_Tank, Vehicle_Tank}, []VehicleType{Vehicle_Tank}, 100 / (2 * 20.0 / 60)},
+		{"fighters on tanks", []VehicleType{Vehicle_Fighter, Vehicle_Fighter}, []VehicleType{Vehicle_Tank}, math.Inf(1)},
+		{"arrvs", []VehicleType{Vehicle_Arrv}, []VehicleType{Vehicle_Ifv, Vehicle_Fighter}, math.Inf(1)},
+		{"no attackers", nil, []VehicleType{Vehicle_Tank}, math.Inf(1)},
+		{"no defenders", []VehicleType{Vehicle_Tank}, nil, 0},
+	}
+
+	for _, tt := range tests {
+		got := TimeToKill(vehicles(g, tt.attackers...), vehicles(g, tt.defender...))
+		if !(got == tt.want || math.Abs(got-tt.want) < 1e-9) {
+			t.Errorf("%s: TimeToKill = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package model
+
+// The functions below build complete moves for every action, starting from the
+// defaults of NewMove, so that only the parameters that matter for the action
+// are set. A strategy copies the result into the move it was given:
+//
+//	*move = *SelectRect(0, 0, 100, 100).OfType(Vehicle_Tank)
+
+// SelectRect selects our vehicles inside the rectangle, clearing the previous
+// selection.
+func SelectRect(left, top, right, bottom float64) *Move {
+	return rectMove(Action_ClearAndSelect, left, top, right, bottom)
+}
+
+// SelectGroup selects the vehicles of the group, clearing the previous
+// selection.
+func SelectGroup(group int) *Move {
+	return groupMove(Action_ClearAndSelect, group)
+}
+
+// AddRect adds our vehicles inside the rectangle to the selection.
+func AddRect(left, top, right, bottom float64) *Move {
+	return rectMove(Action_AddToSelection, left, top, right, bottom)
+}
+
+// AddGroup adds the vehicles of the group to the selection.
+func AddGroup(group int) *Move {
+	return groupMove(Action_AddToSelection, group)
+}
+
+// DeselectRect removes the vehicles inside the rectangle from the selection.
+func DeselectRect(left, top, right, bottom float64) *Move {
+	return rectMove(Action_Deselect, left, top, right, bottom)
+}
+
+// DeselectGroup removes the vehicles of the group from the selection.
+func DeselectGroup(group int) *Move {
+	return groupMove(Action_Deselect, group)
+}
+
+// Assign adds the selected vehicles to the group.
+func Assign(group int) *Move {
+	return groupMove(Action_Assign, group)
+}
+
+// Dismiss removes the selected vehicles from the group.
+func Dismiss(group int) *Move {
+	return groupMove(Action_Dismiss, group)
+}
+
+// Disband removes every vehicle from the group.
+func Disband(group int) *Move {
+	return groupMove(Action_Disband, group)
+}
+
+// MoveBy moves the selected vehicles by (dx, dy). A maxSpeed of 0 means no
+// limit.
+func MoveBy(dx, dy, maxSpeed float64) *Move {
+	m := NewMove()
+	m.Action = Action_Move
+	m.X, m.Y = dx, dy
+	m.MaxSpeed = maxSpeed
+	return m
+}
+
+// RotateAround rotates the selected vehicles clockwise by angle around
+// (x, y). Limit the speed with WithMaxSpeed or WithMaxAngularSpeed.
+func RotateAround(x, y, angle float64) *Move {
+	m := NewMove()
+	m.Action = Action_Rotate
+	m.X, m.Y = x, y
+	m.Angle = angle
+	return m
+}
+
+// ScaleAround scales the formation of the selected vehicles by factor
+// relative to (x, y). Limit the speed with WithMaxSpeed.
+func ScaleAround(x, y, factor float64) *Move {
+	m := NewMove()
+	m.Action = Action_Scale
+	m.X, m.Y = x, y
+	m.Factor = factor
+	return m
+}
+
+// SetupProduction makes the factory build vehicles of the type, resetting its
+// progress. Vehicle_None stops the production.
+func SetupProduction(facilityId int64, t VehicleType) *Move {
+	m := NewMove()
+	m.Action = Action_SetupVehicleProduction
+	m.FacilityId = facilityId
+	m.Type = t
+	return m
+}
+
+// Nuke launches a tactical nuclear strike at (x, y), spotted by our vehicle.
+func Nuke(vehicleId int64, x, y float64) *Move {
+	m := NewMove()
+	m.Action = Action_TacticalNuclearStrike
+	m.VehicleId = vehicleId
+	m.X, m.Y = x, y
+	return m
+}
+
+// OfType limits a rectangle selection to the vehicles of the type.
+func (m *Move) OfType(t VehicleType) *Move {
+	m.Type = t
+	return m
+}
+
+// WithMaxSpeed limits the speed of a move, rotation or scaling.
+func (m *Move) WithMaxSpeed(speed float64) *Move {
+	m.MaxSpeed = speed
+	return m
+}
+
+// WithMaxAngularSpeed limits the angular speed of a rotation in radians per
+// tick.
+func (m *Move) WithMaxAngularSpeed(speed float64) *Move {
+	m.MaxAngularSpeed = speed
+	return m
+}
+
+func rectMove(action ActionType, left, top, right, bottom float64) *Move {
+	m := NewMove()
+	m.Action = action
+	m.Left, m.Top, m.Right, m.Bottom = left, top, right, bottom
+	return m
+}
+
+func groupMove(action ActionType, group int) *Move {
+	m := NewMove()
+	m.Action = action
+	m.Group = group
+	return m
+}
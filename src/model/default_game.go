@@ -0,0 +1,111 @@
+package model
+
+// DefaultGame returns the game constants of the official Russian AI Cup 2017
+// rules for the given random seed, as used by the simulator and the tests.
+func DefaultGame(seed int64) *Game {
+	return &Game{
+		RandomSeed:                            seed,
+		TickCount:                             20000,
+		WorldWidth:                            1024,
+		WorldHeight:                           1024,
+		FogOfWarEnabled:                       false,
+		VictoryScore:                          1000,
+		FacilityCaptureScore:                  100,
+		VehicleEliminationScore:               1,
+		ActionDetectionInterval:               60,
+		BaseActionCount:                       12,
+		AdditionalActionCountPerControlCenter: 3,
+		MaxUnitGroup:                          100,
+		TerrainWeatherMapColumnCount:          32,
+		TerrainWeatherMapRowCount:             32,
+
+		PlainTerrainVisionFactor:   1,
+		PlainTerrainStealthFactor:  1,
+		PlainTerrainSpeedFactor:    1,
+		SwampTerrainVisionFactor:   1,
+		SwampTerrainStealthFactor:  1,
+		SwampTerrainSpeedFactor:    0.6,
+		ForestTerrainVisionFactor:  0.8,
+		ForestTerrainStealthFactor: 0.6,
+		ForestTerrainSpeedFactor:   0.8,
+		ClearWeatherVisionFactor:   1,
+		ClearWeatherStealthFactor:  1,
+		ClearWeatherSpeedFactor:    1,
+		CloudWeatherVisionFactor:   0.8,
+		CloudWeatherStealthFactor:  0.8,
+		CloudWeatherSpeedFactor:    0.8,
+		RainWeatherVisionFactor:    0.6,
+		RainWeatherStealthFactor:   0.6,
+		RainWeatherSpeedFactor:     0.6,
+
+		VehicleRadius: 2,
+
+		TankDurability:          100,
+		TankSpeed:               0.3,
+		TankVisionRange:         80,
+		TankGroundAttackRange:   20,
+		TankAerialAttackRange:   18,
+		TankGroundDamage:        100,
+		TankAerialDamage:        60,
+		TankGroundDefence:       80,
+		TankAerialDefence:       60,
+		TankAttackCooldownTicks: 60,
+		TankProductionCost:      60,
+
+		IFVDurability:          100,
+		IFVSpeed:               0.4,
+		IFVVisionRange:         80,
+		IFVGroundAttackRange:   18,
+		IFVAerialAttackRange:   20,
+		IFVGroundDamage:        90,
+		IFVAerialDamage:        80,
+		IFVGroundDefence:       60,
+		IFVAerialDefence:       80,
+		IFVAttackCooldownTicks: 60,
+		IFVProductionCost:      60,
+
+		ARRVDurability:     100,
+		ARRVSpeed:          0.4,
+		ARRVVisionRange:    60,
+		ARRVGroundDefence:  50,
+		ARRVAerialDefence:  20,
+		ARRVProductionCost: 60,
+		ARRVRepairRange:    10,
+		ARRVRepairSpeed:    0.1,
+
+		HelicopterDurability:          100,
+		HelicopterSpeed:               0.9,
+		HelicopterVisionRange:         100,
+		HelicopterGroundAttackRange:   20,
+		HelicopterAerialAttackRange:   18,
+		HelicopterGroundDamage:        100,
+		HelicopterAerialDamage:        80,
+		HelicopterGroundDefence:       40,
+		HelicopterAerialDefence:       40,
+		HelicopterAttackCooldownTicks: 60,
+		HelicopterProductionCost:      60,
+
+		FighterDurability:          100,
+		FighterSpeed:               1.2,
+		FighterVisionRange:         120,
+		FighterGroundAttackRange:   20,
+		FighterAerialAttackRange:   20,
+		FighterGroundDamage:        0,
+		FighterAerialDamage:        100,
+		FighterGroundDefence:       70,
+		FighterAerialDefence:       70,
+		FighterAttackCooldownTicks: 60,
+		FighterProductionCost:      60,
+
+		MaxFacilityCapturePoints:               100,
+		FacilityCapturePointsPerVehiclePerTick: 0.005,
+		FacilityWidth:                          64,
+		FacilityHeight:                         64,
+
+		BaseTacticalNuclearStrikeCooldown:                     1200,
+		TacticalNuclearStrikeCooldownDecreasePerControlCenter: 60,
+		TacticalNuclearStrikeMaxDamage:                        99,
+		TacticalNuclearStrikeRadius:                           50,
+		TacticalNuclearStrikeDelay:                            30,
+	}
+}
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"math"
+	"sort"
+)
+
+// Sighting is the last known state of an enemy vehicle.
+type Sighting struct {
+	Vehicle
+
+	// Tick is the last tick the vehicle was seen on.
+	Tick int
+
+	// VX and VY is the velocity per tick observed between the last two
+	// sightings.
+	VX, VY float64
+
+	// Visible is set if the vehicle is seen on the current tick.
+	Visible bool
+
+	// Stale is set once the last known position is back in our vision but
+	// the vehicle isn't there any more.
+	Stale bool
+}
+
+// Age returns the number of ticks since the vehicle was last seen.
+func (s *Sighting) Age(tick int) int {
+	return tick - s.Tick
+}
+
+// Reach returns how far the vehicle may have moved from its last known
+// position by the given tick.
+func (s *Sighting) Reach(tick int) float64 {
+	return float64(s.Age(tick)) * s.MaxSpeed
+}
+
+// EnemyMemory remembers enemy vehicles after they leave our vision under the
+// fog of war. Call Update once per tick after World.Vehicles is updated.
+type EnemyMemory struct {
+	// MaxAge is the number of ticks after which an unseen vehicle is
+	// forgotten, 0 to keep it until it is known to be gone.
+	MaxAge int
+
+	game      *Game
+	width     float64
+	height    float64
+	tick      int
+	sightings map[int64]*Sighting
+}
+
+func NewEnemyMemory(g *Game) *EnemyMemory {
+	return &EnemyMemory{
+		game:      g,
+		width:     g.WorldWidth,
+		height:    g.WorldHeight,
+		sightings: make(map[int64]*Sighting),
+	}
+}
+
+func (m *EnemyMemory) Update(w *World) {
+	m.tick = w.TickIndex
+	if w.Width > 0 && w.Height > 0 {
+		m.width, m.height = w.Width, w.Height
+	}
+
+	for _, s := range m.sightings {
+		s.Visible = false
+	}
+
+	for _, v := range w.Vehicles.Enemy() {
+		s, ok := m.sightings[v.Id]
+		if !ok {
+			s = new(Sighting)
+			m.sightings[v.Id] = s
+		} else if dt := m.tick - s.Tick; dt > 0 {
+			s.VX, s.VY = (v.X-s.X)/float64(dt), (v.Y-s.Y)/float64(dt)
+		}
+		s.Vehicle = *v
+		s.Groups = nil
+		s.Tick = m.tick
+		s.Visible = true
+		s.Stale = false
+	}
+
+	// Without the fog of war a vehicle that disappears is destroyed.
+	if !m.game.FogOfWarEnabled {
+		for id, s := range m.sightings {
+			if !s.Visible {
+				delete(m.sightings, id)
+			}
+		}
+		return
+	}
+
+	observers := w.Vehicles.Mine()
+
+	for id, s := range m.sightings {
+		if s.Visible {
+			continue
+		}
+
+		if m.MaxAge > 0 && s.Age(m.tick) > m.MaxAge {
+			delete(m.sightings, id)
+			continue
+		}
+
+		// A vehicle that vanishes from a spot we see well inside our vision
+		// can't have just left it, so it was destroyed.
+		if s.Tick == m.tick-1 && m.observed(w, observers, s, s.MaxSpeed) {
+			delete(m.sightings, id)
+			continue
+		}
+
+		if !s.Stale && m.observed(w, observers, s, 0) {
+			s.Stale = true
+		}
+	}
+}
+
+// observed reports whether the last known position of s is within the vision
+// of one of the observers, with the vision range shortened by margin.
+func (m *EnemyMemory) observed(w *World, observers []*Vehicle, s *Sighting, margin float64) bool {
+	_, stealth, _ := factorsAt(w.Map, s.X, s.Y, s.Aerial)
+
+	for _, o := range observers {
+		vision, _, _ := factorsAt(w.Map, o.X, o.Y, o.Aerial)
+		r := o.VisionRange*vision*stealth - margin
+		if r > 0 && o.GetSquaredDistanceTo(s.X, s.Y) <= r*r {
+			return true
+		}
+	}
+	return false
+}
+
+func factorsAt(m *TerrainMap, x, y float64, aerial bool) (vision, stealth, speed float64) {
+	if m == nil {
+		return 1, 1, 1
+	}
+	return m.Factors(x, y, aerial)
+}
+
+// Predict extrapolates the position of the vehicle at the given tick from its
+// last observed velocity, limited by its speed and the world borders.
+func (m *EnemyMemory) Predict(s *Sighting, tick int) (x, y float64) {
+	dt := float64(s.Age(tick))
+	dx, dy := s.VX*dt, s.VY*dt
+
+	if d, reach := math.Hypot(dx, dy), s.Reach(tick); d > reach && d > 0 {
+		dx, dy = dx/d*reach, dy/d*reach
+	}
+
+	x = math.Max(s.Radius, math.Min(m.width-s.Radius, s.X+dx))
+	y = math.Max(s.Radius, math.Min(m.height-s.Radius, s.Y+dy))
+	return x, y
+}
+
+// Get returns the sighting of the vehicle with the given id or nil.
+func (m *EnemyMemory) Get(id int64) *Sighting {
+	return m.sightings[id]
+}
+
+func (m *EnemyMemory) Len() int {
+	return len(m.sightings)
+}
+
+// All returns every remembered vehicle ordered by id.
+func (m *EnemyMemory) All() []*Sighting {
+	return m.Filter(nil)
+}
+
+// Hidden returns the remembered vehicles not seen on the current tick.
+func (m *EnemyMemory) Hidden() []*Sighting {
+	return m.Filter(func(s *Sighting) bool { return !s.Visible })
+}
+
+// Fresh returns the vehicles seen during the last maxAge ticks that are not
+// known to have left their last position.
+func (m *EnemyMemory) Fresh(maxAge int) []*Sighting {
+	return m.Filter(func(s *Sighting) bool { return !s.Stale && s.Age(m.tick) <= maxAge })
+}
+
+// Filter returns the sightings matching f ordered by id. A nil f matches all.
+func (m *EnemyMemory) Filter(f func(*Sighting) bool) []*Sighting {
+	var result []*Sighting
+	for _, s := range m.sightings {
+		if f == nil || f(s) {
+			result = append(result, s)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result
+}
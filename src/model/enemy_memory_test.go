@@ -0,0 +1,122 @@
+package model
+
+import "testing"
+
+// memoryWorld returns the world of the tick in which exactly the given
+// vehicles are visible, ours belonging to player 1.
+func memoryWorld(tick int, vehicles ...*Vehicle) *World {
+	w := &World{
+		TickIndex:   tick,
+		Width:       1024,
+		Height:      1024,
+		Players:     []*Player{{Id: 1, Me: true}, {Id: 2}},
+		NewVehicles: vehicles,
+		Vehicles:    NewVehicleRegistry(),
+	}
+	w.Vehicles.Update(w)
+	return w
+}
+
+func memoryVehicle(g *Game, id, player int64, x, y float64) *Vehicle {
+	v := g.NewVehicle(Vehicle_Tank)
+	v.Id, v.PlayerId, v.X, v.Y = id, player, x, y
+	return v
+}
+
+func TestEnemyMemoryWithoutFog(t *testing.T) {
+	g := DefaultGame(1)
+	m := NewEnemyMemory(g)
+
+	m.Update(memoryWorld(0, memoryVehicle(g, 1, 2, 100, 100)))
+	m.Update(memoryWorld(1))
+	if m.Len() != 0 {
+		t.Errorf("without the fog of war a vanished vehicle is remembered: %+v", m.All())
+	}
+}
+
+func TestEnemyMemoryLastSeen(t *testing.T) {
+	g := DefaultGame(1)
+	g.FogOfWarEnabled = true
+	m := NewEnemyMemory(g)
+
+	// Seen on two ticks, moving right at 0.2 per tick, then lost out of
+	// the vision of our far away tank.
+	ours := memoryVehicle(g, 10, 1, 800, 800)
+	m.Update(memoryWorld(0, ours, memoryVehicle(g, 1, 2, 100, 100)))
+	m.Update(memoryWorld(1, ours, memoryVehicle(g, 1, 2, 100.2, 100)))
+	m.Update(memoryWorld(2, ours))
+
+	s := m.Get(1)
+	if s == nil {
+		t.Fatal("hidden vehicle forgotten")
+	}
+	if s.Visible || s.Stale || s.Tick != 1 || s.X != 100.2 || s.Age(2) != 1 {
+		t.Errorf("sighting %+v, want the hidden vehicle last seen at 100.2 on tick 1", s)
+	}
+	if hidden := m.Hidden(); len(hidden) != 1 || hidden[0] != s {
+		t.Errorf("Hidden() = %v, want the sighting", hidden)
+	}
+	if x, y := m.Predict(s, 11); x < 102.19 || x > 102.21 || y != 100 {
+		t.Errorf("Predict after 10 ticks = %v, %v, want 102.2, 100", x, y)
+	}
+
+	// The position is limited by the speed and the world borders.
+	s.VX, s.VY = -5, 0
+	if x, _ := m.Predict(s, 11); x != 100.2-10*s.MaxSpeed {
+		t.Errorf("Predict faster than the vehicle = %v, want %v", x, 100.2-10*s.MaxSpeed)
+	}
+	if x, _ := m.Predict(s, 1000); x != s.Radius {
+		t.Errorf("Predict past the border = %v, want %v", x, s.Radius)
+	}
+
+	// Our tank comes to look: the vehicle isn't there any more.
+	ours = memoryVehicle(g, 10, 1, 150, 100)
+	m.Update(memoryWorld(5, ours))
+	if s := m.Get(1); s == nil || !s.Stale {
+		t.Errorf("sighting %+v, want it stale once its position is back in our vision", s)
+	}
+	if fresh := m.Fresh(100); len(fresh) != 0 {
+		t.Errorf("Fresh() = %v, want no stale sighting", fresh)
+	}
+
+	// Seen again, it isn't stale any more.
+	m.Update(memoryWorld(6, ours, memoryVehicle(g, 1, 2, 120, 100)))
+	if s := m.Get(1); s == nil || !s.Visible || s.Stale || len(m.Fresh(0)) != 1 {
+		t.Errorf("sighting %+v, want it visible and fresh", s)
+	}
+}
+
+func TestEnemyMemoryDestroyed(t *testing.T) {
+	g := DefaultGame(1)
+	g.FogOfWarEnabled = true
+	m := NewEnemyMemory(g)
+
+	// A vehicle vanishing right next to our tank can't have left on time.
+	ours := memoryVehicle(g, 10, 1, 100, 100)
+	m.Update(memoryWorld(0, ours, memoryVehicle(g, 1, 2, 120, 100), memoryVehicle(g, 2, 2, 500, 500)))
+	m.Update(memoryWorld(1, ours))
+
+	if m.Get(1) != nil {
+		t.Errorf("vehicle vanished in plain sight still remembered: %+v", m.Get(1))
+	}
+	if m.Get(2) == nil {
+		t.Error("vehicle out of sight forgotten")
+	}
+}
+
+func TestEnemyMemoryMaxAge(t *testing.T) {
+	g := DefaultGame(1)
+	g.FogOfWarEnabled = true
+	m := NewEnemyMemory(g)
+	m.MaxAge = 10
+
+	m.Update(memoryWorld(0, memoryVehicle(g, 1, 2, 100, 100)))
+	m.Update(memoryWorld(10))
+	if m.Get(1) == nil {
+		t.Error("vehicle forgotten at MaxAge")
+	}
+	m.Update(memoryWorld(11))
+	if m.Len() != 0 {
+		t.Errorf("vehicle remembered past MaxAge: %+v", m.All())
+	}
+}
@@ -414,3 +414,121 @@ type Game struct {
 	 */
 	TacticalNuclearStrikeDelay int
 }
+
+/**
+ * @return Возвращает коэффициенты радиуса обзора, скрытности и скорости наземной техники, находящейся на местности
+ * заданного типа.
+ */
+func (g *Game) TerrainFactors(t Terrain) (vision, stealth, speed float64) {
+	switch t {
+	case Terrain_Swamp:
+		return g.SwampTerrainVisionFactor, g.SwampTerrainStealthFactor, g.SwampTerrainSpeedFactor
+	case Terrain_Forest:
+		return g.ForestTerrainVisionFactor, g.ForestTerrainStealthFactor, g.ForestTerrainSpeedFactor
+	}
+	return g.PlainTerrainVisionFactor, g.PlainTerrainStealthFactor, g.PlainTerrainSpeedFactor
+}
+
+/**
+ * @return Возвращает коэффициенты радиуса обзора, скрытности и скорости воздушной техники, находящейся в области
+ * заданной погоды.
+ */
+func (g *Game) WeatherFactors(w Weather) (vision, stealth, speed float64) {
+	switch w {
+	case Weather_Cloud:
+		return g.CloudWeatherVisionFactor, g.CloudWeatherStealthFactor, g.CloudWeatherSpeedFactor
+	case Weather_Rain:
+		return g.RainWeatherVisionFactor, g.RainWeatherStealthFactor, g.RainWeatherSpeedFactor
+	}
+	return g.ClearWeatherVisionFactor, g.ClearWeatherStealthFactor, g.ClearWeatherSpeedFactor
+}
+
+/**
+ * @return Возвращает новую технику заданного типа с полной прочностью и характеристиками из игровых констант.
+ * Идентификатор, владелец и координаты техники не заданы.
+ */
+func (g *Game) NewVehicle(t VehicleType) *Vehicle {
+	v := &Vehicle{Type: t}
+	v.Radius = g.VehicleRadius
+
+	switch t {
+	case Vehicle_Arrv:
+		v.MaxDurability = g.ARRVDurability
+		v.MaxSpeed = g.ARRVSpeed
+		v.VisionRange = g.ARRVVisionRange
+		v.GroundDefence = g.ARRVGroundDefence
+		v.AerialDefence = g.ARRVAerialDefence
+	case Vehicle_Fighter:
+		v.MaxDurability = g.FighterDurability
+		v.MaxSpeed = g.FighterSpeed
+		v.VisionRange = g.FighterVisionRange
+		v.GroundAttackRange = g.FighterGroundAttackRange
+		v.AerialAttackRange = g.FighterAerialAttackRange
+		v.GroundDamage = g.FighterGroundDamage
+		v.AerialDamage = g.FighterAerialDamage
+		v.GroundDefence = g.FighterGroundDefence
+		v.AerialDefence = g.FighterAerialDefence
+		v.AttackCooldownTicks = g.FighterAttackCooldownTicks
+		v.Aerial = true
+	case Vehicle_Helicopter:
+		v.MaxDurability = g.HelicopterDurability
+		v.MaxSpeed = g.HelicopterSpeed
+		v.VisionRange = g.HelicopterVisionRange
+		v.GroundAttackRange = g.HelicopterGroundAttackRange
+		v.AerialAttackRange = g.HelicopterAerialAttackRange
+		v.GroundDamage = g.HelicopterGroundDamage
+		v.AerialDamage = g.HelicopterAerialDamage
+		v.GroundDefence = g.HelicopterGroundDefence
+		v.AerialDefence = g.HelicopterAerialDefence
+		v.AttackCooldownTicks = g.HelicopterAttackCooldownTicks
+		v.Aerial = true
+	case Vehicle_Ifv:
+		v.MaxDurability = g.IFVDurability
+		v.MaxSpeed = g.IFVSpeed
+		v.VisionRange = g.IFVVisionRange
+		v.GroundAttackRange = g.IFVGroundAttackRange
+		v.AerialAttackRange = g.IFVAerialAttackRange
+		v.GroundDamage = g.IFVGroundDamage
+		v.AerialDamage = g.IFVAerialDamage
+		v.GroundDefence = g.IFVGroundDefence
+		v.AerialDefence = g.IFVAerialDefence
+		v.AttackCooldownTicks = g.IFVAttackCooldownTicks
+	case Vehicle_Tank:
+		v.MaxDurability = g.TankDurability
+		v.MaxSpeed = g.TankSpeed
+		v.VisionRange = g.TankVisionRange
+		v.GroundAttackRange = g.TankGroundAttackRange
+		v.AerialAttackRange = g.TankAerialAttackRange
+		v.GroundDamage = g.TankGroundDamage
+		v.AerialDamage = g.TankAerialDamage
+		v.GroundDefence = g.TankGroundDefence
+		v.AerialDefence = g.TankAerialDefence
+		v.AttackCooldownTicks = g.TankAttackCooldownTicks
+	}
+
+	v.Durability = v.MaxDurability
+	v.SquaredVisionRange = v.VisionRange * v.VisionRange
+	v.SquaredGroundAttackRange = v.GroundAttackRange * v.GroundAttackRange
+	v.SquaredAerialAttackRange = v.AerialAttackRange * v.AerialAttackRange
+
+	return v
+}
+
+/**
+ * @return Возвращает количество тиков, необходимое для производства техники заданного типа.
+ */
+func (g *Game) ProductionCost(t VehicleType) int {
+	switch t {
+	case Vehicle_Arrv:
+		return g.ARRVProductionCost
+	case Vehicle_Fighter:
+		return g.FighterProductionCost
+	case Vehicle_Helicopter:
+		return g.HelicopterProductionCost
+	case Vehicle_Ifv:
+		return g.IFVProductionCost
+	case Vehicle_Tank:
+		return g.TankProductionCost
+	}
+	return 0
+}
@@ -0,0 +1,155 @@
+package model
+
+import "sort"
+
+// GroupInfo describes the own vehicles of one group.
+type GroupInfo struct {
+	Group int
+
+	// Ids of the members ordered by id.
+	Ids []int64
+
+	CenterX, CenterY         float64
+	Left, Top, Right, Bottom float64
+
+	// Composition counts the members of every type.
+	Composition map[VehicleType]int
+
+	Durability    int
+	MaxDurability int
+}
+
+// Health returns the durability of the group as a fraction of its maximum.
+func (g *GroupInfo) Health() float64 {
+	if g.MaxDurability == 0 {
+		return 0
+	}
+	return float64(g.Durability) / float64(g.MaxDurability)
+}
+
+// GroupManager indexes our vehicles by group and hands out free group
+// numbers. Call Update once per tick after World.Vehicles is updated.
+type GroupManager struct {
+	game     *Game
+	groups   map[int]*GroupInfo
+	reserved map[int]bool
+}
+
+func NewGroupManager(g *Game) *GroupManager {
+	return &GroupManager{
+		game:     g,
+		groups:   make(map[int]*GroupInfo),
+		reserved: make(map[int]bool),
+	}
+}
+
+func (m *GroupManager) Update(w *World) {
+	m.groups = make(map[int]*GroupInfo)
+
+	for _, v := range w.Vehicles.Mine() {
+		for _, group := range v.Groups {
+			g, ok := m.groups[group]
+			if !ok {
+				g = &GroupInfo{
+					Group:       group,
+					Left:        v.X,
+					Top:         v.Y,
+					Right:       v.X,
+					Bottom:      v.Y,
+					Composition: make(map[VehicleType]int),
+				}
+				m.groups[group] = g
+			}
+
+			g.Ids = append(g.Ids, v.Id)
+			g.CenterX += v.X
+			g.CenterY += v.Y
+			if v.X < g.Left {
+				g.Left = v.X
+			}
+			if v.X > g.Right {
+				g.Right = v.X
+			}
+			if v.Y < g.Top {
+				g.Top = v.Y
+			}
+			if v.Y > g.Bottom {
+				g.Bottom = v.Y
+			}
+			g.Composition[v.Type]++
+			g.Durability += v.Durability
+			g.MaxDurability += v.MaxDurability
+		}
+	}
+
+	for group, g := range m.groups {
+		n := float64(len(g.Ids))
+		g.CenterX /= n
+		g.CenterY /= n
+		delete(m.reserved, group)
+	}
+}
+
+// Get returns the group or nil if it has no vehicles.
+func (m *GroupManager) Get(group int) *GroupInfo {
+	return m.groups[group]
+}
+
+// Groups returns the numbers of the groups that have vehicles, in order.
+func (m *GroupManager) Groups() []int {
+	groups := make([]int, 0, len(m.groups))
+	for group := range m.groups {
+		groups = append(groups, group)
+	}
+	sort.Ints(groups)
+	return groups
+}
+
+// Allocate reserves the lowest group number that has no vehicles and isn't
+// reserved, or returns 0 if all MaxUnitGroup numbers are taken. The
+// reservation ends once the group gets vehicles or is released.
+func (m *GroupManager) Allocate() int {
+	for group := 1; group <= m.game.MaxUnitGroup; group++ {
+		if m.groups[group] == nil && !m.reserved[group] {
+			m.reserved[group] = true
+			return group
+		}
+	}
+	return 0
+}
+
+// Release gives back a group number reserved by Allocate.
+func (m *GroupManager) Release(group int) {
+	delete(m.reserved, group)
+}
+
+// The methods below return the moves to send, one per tick, to change the
+// groups. Each sequence starts with a new selection.
+
+// Form assigns the vehicles of the given type in the rectangle to the group.
+// Vehicle_None stands for any type.
+func (m *GroupManager) Form(group int, left, top, right, bottom float64, t VehicleType) []*Move {
+	return []*Move{SelectRect(left, top, right, bottom).OfType(t), Assign(group)}
+}
+
+// Merge moves every vehicle of the group from into the group into and
+// disbands from.
+func (m *GroupManager) Merge(into, from int) []*Move {
+	return []*Move{SelectGroup(from), Assign(into), Disband(from)}
+}
+
+// Split keeps in the group from the members of the given type in the
+// rectangle and moves the other members to the group to.
+func (m *GroupManager) Split(from, to int, left, top, right, bottom float64, t VehicleType) []*Move {
+	return []*Move{
+		SelectGroup(from),
+		DeselectRect(left, top, right, bottom).OfType(t),
+		Dismiss(from),
+		Assign(to),
+	}
+}
+
+// Disband removes every vehicle from the group.
+func (m *GroupManager) Disband(group int) []*Move {
+	return []*Move{Disband(group)}
+}
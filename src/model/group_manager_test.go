@@ -0,0 +1,163 @@
+package model
+
+import "testing"
+
+// applyGroupMoves performs the selection and group moves on the vehicles like
+// the server does.
+func applyGroupMoves(vehicles []*Vehicle, moves []*Move) {
+	for _, m := range moves {
+		applySelection(vehicles, []*Move{m})
+		for _, v := range vehicles {
+			switch {
+			case m.Action == Action_Assign && v.Selected && !v.InGroup(m.Group):
+				v.Groups = append(v.Groups, m.Group)
+			case m.Action == Action_Dismiss && v.Selected, m.Action == Action_Disband:
+				var groups []int
+				for _, g := range v.Groups {
+					if g != m.Group {
+						groups = append(groups, g)
+					}
+				}
+				v.Groups = groups
+			}
+		}
+	}
+}
+
+// groupMembers returns the ids of the vehicles in the group in order.
+func groupMembers(vehicles []*Vehicle, group int) []int64 {
+	var ids []int64
+	for _, v := range vehicles {
+		if v.InGroup(group) {
+			ids = append(ids, v.Id)
+		}
+	}
+	return ids
+}
+
+// groupVehicles returns our tanks 1 to 3 in group 1 and IFVs 4 and 5 in group
+// 2, in a row 10 apart.
+func groupVehicles(g *Game) []*Vehicle {
+	var vehicles []*Vehicle
+	for i := 1; i <= 5; i++ {
+		vt, group := Vehicle_Tank, 1
+		if i > 3 {
+			vt, group = Vehicle_Ifv, 2
+		}
+		v := g.NewVehicle(vt)
+		v.Id, v.PlayerId, v.X, v.Y, v.Groups = int64(i), 1, float64(10*i), 10, []int{group}
+		vehicles = append(vehicles, v)
+	}
+	vehicles[0].Durability = 50
+	return vehicles
+}
+
+func TestGroupManagerUpdate(t *testing.T) {
+	g := DefaultGame(1)
+	vehicles := groupVehicles(g)
+	// Our vehicles may be in several groups, enemies don't count.
+	vehicles[3].Groups = append(vehicles[3].Groups, 1)
+	enemy := g.NewVehicle(Vehicle_Tank)
+	enemy.Id, enemy.PlayerId, enemy.Groups = 6, 2, []int{1}
+
+	m := NewGroupManager(g)
+	m.Update(selectionWorld(append(vehicles, enemy)))
+
+	if got := m.Groups(); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("Groups() = %v, want [1 2]", got)
+	}
+	info := m.Get(1)
+	if !equalIds(info.Ids, []int64{1, 2, 3, 4}) {
+		t.Errorf("group 1 has %v, want [1 2 3 4]", info.Ids)
+	}
+	if info.CenterX != 25 || info.CenterY != 10 || info.Left != 10 || info.Right != 40 || info.Top != 10 || info.Bottom != 10 {
+		t.Errorf("group 1 centred at %v, %v within %v, %v, %v, %v", info.CenterX, info.CenterY, info.Left, info.Top, info.Right, info.Bottom)
+	}
+	if info.Composition[Vehicle_Tank] != 3 || info.Composition[Vehicle_Ifv] != 1 {
+		t.Errorf("group 1 composition %v, want 3 tanks and an IFV", info.Composition)
+	}
+	if want := 3*g.TankDurability + g.IFVDurability; info.MaxDurability != want || info.Durability != want-g.TankDurability+50 {
+		t.Errorf("group 1 durability %d of %d", info.Durability, info.MaxDurability)
+	}
+	if h := info.Health(); h <= 0 || h >= 1 {
+		t.Errorf("group 1 health %v, want between 0 and 1", h)
+	}
+	if m.Get(3) != nil {
+		t.Errorf("empty group 3: %+v", m.Get(3))
+	}
+}
+
+func TestGroupManagerAllocate(t *testing.T) {
+	g := DefaultGame(1)
+	g.MaxUnitGroup = 4
+	vehicles := groupVehicles(g)
+	m := NewGroupManager(g)
+	m.Update(selectionWorld(vehicles))
+
+	// Groups 1 and 2 have vehicles, 3 and 4 are free.
+	if a, b, c := m.Allocate(), m.Allocate(), m.Allocate(); a != 3 || b != 4 || c != 0 {
+		t.Fatalf("Allocate() = %d, %d, %d, want 3, 4, 0", a, b, c)
+	}
+
+	// A released number is handed out again.
+	m.Release(4)
+	if got := m.Allocate(); got != 4 {
+		t.Errorf("Allocate() = %d after releasing 4, want 4", got)
+	}
+
+	// The reservation of 3 ends once it has vehicles, and it stays taken
+	// while it has them; group 2 is free again once emptied.
+	applyGroupMoves(vehicles, m.Form(3, 0, 0, 20, 20, Vehicle_None))
+	applyGroupMoves(vehicles, m.Disband(2))
+	m.Update(selectionWorld(vehicles))
+	m.Release(4)
+	if a, b, c := m.Allocate(), m.Allocate(), m.Allocate(); a != 2 || b != 4 || c != 0 {
+		t.Errorf("Allocate() = %d, %d, %d, want 2, 4, 0", a, b, c)
+	}
+}
+
+func TestGroupManagerMoves(t *testing.T) {
+	g := DefaultGame(1)
+	m := NewGroupManager(g)
+
+	tests := []struct {
+		name  string
+		moves func() []*Move
+		want  map[int][]int64
+	}{
+		{
+			name:  "form",
+			moves: func() []*Move { return m.Form(3, 0, 0, 35, 20, Vehicle_Tank) },
+			want:  map[int][]int64{1: {1, 2, 3}, 2: {4, 5}, 3: {1, 2, 3}},
+		},
+		{
+			name:  "merge",
+			moves: func() []*Move { return m.Merge(1, 2) },
+			want:  map[int][]int64{1: {1, 2, 3, 4, 5}, 2: nil},
+		},
+		{
+			name:  "split",
+			moves: func() []*Move { return m.Split(1, 3, 0, 0, 25, 20, Vehicle_Tank) },
+			want:  map[int][]int64{1: {1, 2}, 2: {4, 5}, 3: {3}},
+		},
+		{
+			name:  "disband",
+			moves: func() []*Move { return m.Disband(1) },
+			want:  map[int][]int64{1: nil, 2: {4, 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		vehicles := groupVehicles(g)
+		// Every sequence starts with a new selection, whatever was
+		// selected before.
+		vehicles[4].Selected = true
+		applyGroupMoves(vehicles, tt.moves())
+
+		for group, want := range tt.want {
+			if got := groupMembers(vehicles, group); !equalIds(got, want) {
+				t.Errorf("%s: group %d has %v, want %v", tt.name, group, got, want)
+			}
+		}
+	}
+}
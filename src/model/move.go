@@ -176,3 +176,16 @@ type Move struct {
 	 */
 	VehicleId int64
 }
+
+/**
+ * @return Возвращает новое действие со значениями параметров по умолчанию.
+ */
+func NewMove() *Move {
+	return &Move{
+		Type:       Vehicle_None,
+		Action:     Action_None,
+		Factor:     1,
+		FacilityId: -1,
+		VehicleId:  -1,
+	}
+}
@@ -0,0 +1,345 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type Violation int
+
+const (
+	// Violation_ActionCooldown: the player can't act before
+	// RemainingActionCooldownTicks runs out.
+	Violation_ActionCooldown Violation = iota
+
+	// Violation_Action: the action is unknown.
+	Violation_Action
+
+	// Violation_Group: the group is outside 1..MaxUnitGroup.
+	Violation_Group
+
+	// Violation_Rect: the selection rectangle is inverted or leaves the
+	// world.
+	Violation_Rect
+
+	// Violation_VehicleType: the vehicle type is unknown.
+	Violation_VehicleType
+
+	// Violation_EmptySelection: the action applies to the selected vehicles
+	// but none of ours is selected.
+	Violation_EmptySelection
+
+	// Violation_Displacement: X or Y of Action_Move is longer than the world.
+	Violation_Displacement
+
+	// Violation_Centre: the centre of Action_Rotate or Action_Scale is too
+	// far outside the world.
+	Violation_Centre
+
+	// Violation_Angle: Angle is outside -PI..PI.
+	Violation_Angle
+
+	// Violation_Factor: Factor is outside 0.1..10.
+	Violation_Factor
+
+	// Violation_MaxSpeed: MaxSpeed is negative.
+	Violation_MaxSpeed
+
+	// Violation_MaxAngularSpeed: MaxAngularSpeed is outside 0..PI.
+	Violation_MaxAngularSpeed
+
+	// Violation_Facility: the facility isn't one of our vehicle factories.
+	Violation_Facility
+
+	// Violation_NuclearCooldown: the nuclear strike is still cooling down or
+	// another strike is on its way.
+	Violation_NuclearCooldown
+
+	// Violation_Spotter: the spotting vehicle isn't one of ours.
+	Violation_Spotter
+
+	// Violation_Target: the strike target is outside the world or out of the
+	// sight of the spotter.
+	Violation_Target
+)
+
+var violationNames = []string{
+	"action cooldown", "unknown action", "invalid group", "invalid selection rectangle", "unknown vehicle type",
+	"empty selection", "displacement out of range", "centre out of range", "angle out of range",
+	"factor out of range", "negative max speed", "max angular speed out of range", "not our vehicle factory",
+	"nuclear strike not ready", "spotter not ours", "target out of reach",
+}
+
+func (v Violation) String() string {
+	if v >= 0 && int(v) < len(violationNames) {
+		return violationNames[v]
+	}
+	return fmt.Sprintf("Violation(%d)", int(v))
+}
+
+// MoveError is one way in which a move breaks the game rules. The server
+// ignores such a move but still counts it against the action limit.
+type MoveError struct {
+	Action    ActionType
+	Violation Violation
+
+	// Repaired is set by MoveValidator.Repair when it fixed the violation.
+	Repaired bool
+}
+
+func (e *MoveError) Error() string {
+	if e.Repaired {
+		return fmt.Sprintf("move %s: %v (repaired)", e.Action.String(), e.Violation)
+	}
+	return fmt.Sprintf("move %s: %v", e.Action.String(), e.Violation)
+}
+
+// MoveErrors lists every violation of a move. Use errors.As to look for a
+// particular MoveError.
+type MoveErrors []*MoveError
+
+func (errs MoveErrors) Error() string {
+	s := make([]string, len(errs))
+	for i, e := range errs {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+func (errs MoveErrors) Unwrap() []error {
+	u := make([]error, len(errs))
+	for i, e := range errs {
+		u[i] = e
+	}
+	return u
+}
+
+// Has reports whether the violation is listed.
+func (errs MoveErrors) Has(v Violation) bool {
+	for _, e := range errs {
+		if e.Violation == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MoveValidator checks moves against the rules of the game before they are
+// sent, so that mistakes don't silently waste actions.
+type MoveValidator struct {
+	game *Game
+}
+
+func NewMoveValidator(g *Game) *MoveValidator {
+	return &MoveValidator{game: g}
+}
+
+// Validate returns the violations of the move as MoveErrors, or nil if the
+// server will accept it. The checks that need our vehicles are skipped when
+// w.Vehicles isn't set.
+func (c *MoveValidator) Validate(p *Player, w *World, m *Move) error {
+	return c.check(p, w, m, false)
+}
+
+// Repair fixes what can be fixed without changing the intent of the move:
+// it clamps the selection rectangle, the displacement, the centre and the
+// speeds to their ranges, wraps the angle and clamps a set factor. If any
+// violation remains, the move is reset to Action_None so that it doesn't cost
+// an action. The returned MoveErrors lists every violation found, fixed or
+// not.
+func (c *MoveValidator) Repair(p *Player, w *World, m *Move) error {
+	err := c.check(p, w, m, true)
+	if err == nil {
+		return nil
+	}
+	for _, e := range err.(MoveErrors) {
+		if !e.Repaired {
+			*m = *NewMove()
+			break
+		}
+	}
+	return err
+}
+
+func (c *MoveValidator) check(p *Player, w *World, m *Move, repair bool) error {
+	var errs MoveErrors
+	report := func(v Violation, fix func()) {
+		e := &MoveError{Action: m.Action, Violation: v}
+		if repair && fix != nil {
+			fix()
+			e.Repaired = true
+		}
+		errs = append(errs, e)
+	}
+
+	if m.Action == Action_None {
+		return nil
+	}
+	if p.RemainingActionCooldownTicks > 0 {
+		report(Violation_ActionCooldown, nil)
+	}
+
+	g := c.game
+	switch m.Action {
+	case Action_ClearAndSelect, Action_AddToSelection, Action_Deselect:
+		if m.Group != 0 {
+			c.checkGroup(m, report)
+			break
+		}
+		if !(m.Left >= 0 && m.Left <= m.Right && m.Right <= g.WorldWidth &&
+			m.Top >= 0 && m.Top <= m.Bottom && m.Bottom <= g.WorldHeight) {
+			report(Violation_Rect, func() {
+				m.Left, m.Right = clampRange(m.Left, m.Right, g.WorldWidth)
+				m.Top, m.Bottom = clampRange(m.Top, m.Bottom, g.WorldHeight)
+			})
+		}
+		if !validVehicleType(m.Type) {
+			report(Violation_VehicleType, nil)
+		}
+
+	case Action_Assign, Action_Dismiss:
+		c.checkGroup(m, report)
+		c.checkSelection(w, report)
+
+	case Action_Disband:
+		c.checkGroup(m, report)
+
+	case Action_Move:
+		if !(math.Abs(m.X) <= g.WorldWidth && math.Abs(m.Y) <= g.WorldHeight) {
+			report(Violation_Displacement, func() {
+				m.X = clamp(m.X, -g.WorldWidth, g.WorldWidth)
+				m.Y = clamp(m.Y, -g.WorldHeight, g.WorldHeight)
+			})
+		}
+		c.checkMaxSpeed(m, report)
+		c.checkSelection(w, report)
+
+	case Action_Rotate:
+		c.checkCentre(m, report)
+		if !(math.Abs(m.Angle) <= math.Pi) {
+			fix := func() { m.Angle = math.Remainder(m.Angle, 2*math.Pi) }
+			if math.IsNaN(m.Angle) || math.IsInf(m.Angle, 0) {
+				fix = nil
+			}
+			report(Violation_Angle, fix)
+		}
+		c.checkMaxSpeed(m, report)
+		if !(m.MaxAngularSpeed >= 0 && m.MaxAngularSpeed <= math.Pi) {
+			report(Violation_MaxAngularSpeed, func() { m.MaxAngularSpeed = clamp(m.MaxAngularSpeed, 0, math.Pi) })
+		}
+		c.checkSelection(w, report)
+
+	case Action_Scale:
+		c.checkCentre(m, report)
+		if !(m.Factor >= 0.1 && m.Factor <= 10) {
+			// An unset factor says nothing about the intended scale.
+			fix := func() { m.Factor = clamp(m.Factor, 0.1, 10) }
+			if !(m.Factor > 0) {
+				fix = nil
+			}
+			report(Violation_Factor, fix)
+		}
+		c.checkMaxSpeed(m, report)
+		c.checkSelection(w, report)
+
+	case Action_SetupVehicleProduction:
+		ok := false
+		for _, f := range w.Facilities {
+			if f.Id == m.FacilityId {
+				ok = f.FacilityType == Facility_VehicleFactory && f.OwnerPlayerId == p.Id
+			}
+		}
+		if !ok {
+			report(Violation_Facility, nil)
+		}
+		if !validVehicleType(m.Type) {
+			report(Violation_VehicleType, nil)
+		}
+
+	case Action_TacticalNuclearStrike:
+		if p.RemainingNuclearStrikeCooldownTicks > 0 || p.NextNuclearStrikeTickIndex >= 0 {
+			report(Violation_NuclearCooldown, nil)
+		}
+		if !(m.X >= 0 && m.X <= g.WorldWidth && m.Y >= 0 && m.Y <= g.WorldHeight) {
+			report(Violation_Target, nil)
+		}
+		if w.Vehicles != nil {
+			v := w.Vehicles.Get(m.VehicleId)
+			if v == nil || v.PlayerId != p.Id {
+				report(Violation_Spotter, nil)
+				break
+			}
+			vision, _, _ := factorsAt(w.Map, v.X, v.Y, v.Aerial)
+			if r := v.VisionRange * vision; v.GetSquaredDistanceTo(m.X, m.Y) > r*r && !errs.Has(Violation_Target) {
+				report(Violation_Target, nil)
+			}
+		}
+
+	default:
+		report(Violation_Action, nil)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (c *MoveValidator) checkGroup(m *Move, report func(Violation, func())) {
+	if !(m.Group >= 1 && m.Group <= c.game.MaxUnitGroup) {
+		report(Violation_Group, nil)
+	}
+}
+
+func (c *MoveValidator) checkCentre(m *Move, report func(Violation, func())) {
+	g := c.game
+	if !(m.X >= -g.WorldWidth && m.X <= 2*g.WorldWidth && m.Y >= -g.WorldHeight && m.Y <= 2*g.WorldHeight) {
+		report(Violation_Centre, func() {
+			m.X = clamp(m.X, -g.WorldWidth, 2*g.WorldWidth)
+			m.Y = clamp(m.Y, -g.WorldHeight, 2*g.WorldHeight)
+		})
+	}
+}
+
+func (c *MoveValidator) checkMaxSpeed(m *Move, report func(Violation, func())) {
+	if !(m.MaxSpeed >= 0) {
+		report(Violation_MaxSpeed, func() { m.MaxSpeed = 0 })
+	}
+}
+
+func (c *MoveValidator) checkSelection(w *World, report func(Violation, func())) {
+	if w.Vehicles == nil {
+		return
+	}
+	for _, v := range w.Vehicles.Mine() {
+		if v.Selected {
+			return
+		}
+	}
+	report(Violation_EmptySelection, nil)
+}
+
+func validVehicleType(t VehicleType) bool {
+	return t == Vehicle_None || t <= Vehicle_Tank
+}
+
+// clamp returns x limited to lo..hi, lo for NaN.
+func clamp(x, lo, hi float64) float64 {
+	if !(x >= lo) {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
+
+// clampRange orders the bounds and limits them to 0..max.
+func clampRange(lo, hi, max float64) (float64, float64) {
+	lo, hi = clamp(lo, 0, max), clamp(hi, 0, max)
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return lo, hi
+}
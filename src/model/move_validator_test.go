@@ -0,0 +1,266 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// validatorWorld returns a world with a tank of each player, ours at
+// (100, 100), an IFV factory of each player and a control center of ours.
+func validatorWorld(g *Game, selected bool) (*Player, *World) {
+	me := &Player{Id: 1, Me: true, NextNuclearStrikeTickIndex: -1, NextNuclearStrikeVehicleId: -1}
+	enemy := &Player{Id: 2, NextNuclearStrikeTickIndex: -1, NextNuclearStrikeVehicleId: -1}
+
+	tank, enemyTank := g.NewVehicle(Vehicle_Tank), g.NewVehicle(Vehicle_Tank)
+	tank.Id, tank.PlayerId, tank.X, tank.Y, tank.Selected = 1, me.Id, 100, 100, selected
+	enemyTank.Id, enemyTank.PlayerId, enemyTank.X, enemyTank.Y = 2, enemy.Id, 150, 100
+
+	w := &World{
+		Width:       g.WorldWidth,
+		Height:      g.WorldHeight,
+		Players:     []*Player{me, enemy},
+		NewVehicles: []*Vehicle{tank, enemyTank},
+		Facilities: []*Facility{
+			{Id: 1, FacilityType: Facility_VehicleFactory, OwnerPlayerId: me.Id},
+			{Id: 2, FacilityType: Facility_VehicleFactory, OwnerPlayerId: enemy.Id},
+			{Id: 3, FacilityType: Facility_ControlCenter, OwnerPlayerId: me.Id},
+		},
+		Vehicles: NewVehicleRegistry(),
+	}
+	w.Vehicles.Update(w)
+	return me, w
+}
+
+func TestMoveValidator(t *testing.T) {
+	g := DefaultGame(1)
+
+	tests := []struct {
+		name       string
+		player     func(p *Player)
+		unselected bool
+		move       func(m *Move)
+		want       Violation
+
+		// repaired checks the move after Repair. A nil repaired means the
+		// violation can't be repaired and the move must be reset.
+		repaired func(m *Move) bool
+	}{
+		{
+			name:   "action cooldown",
+			player: func(p *Player) { p.RemainingActionCooldownTicks = 3 },
+			move:   func(m *Move) { m.Action, m.Right, m.Bottom = Action_ClearAndSelect, 100, 100 },
+			want:   Violation_ActionCooldown,
+		},
+		{
+			name: "unknown action",
+			move: func(m *Move) { m.Action = ActionType(42) },
+			want: Violation_Action,
+		},
+		{
+			name: "group 0",
+			move: func(m *Move) { m.Action, m.Group = Action_Assign, 0 },
+			want: Violation_Group,
+		},
+		{
+			name: "group above MaxUnitGroup",
+			move: func(m *Move) { m.Action, m.Group = Action_Disband, g.MaxUnitGroup+1 },
+			want: Violation_Group,
+		},
+		{
+			name: "selection by group outside the range",
+			move: func(m *Move) { m.Action, m.Group = Action_ClearAndSelect, -1 },
+			want: Violation_Group,
+		},
+		{
+			name:     "left beyond right",
+			move:     func(m *Move) { m.Action, m.Left, m.Right, m.Bottom = Action_ClearAndSelect, 200, 100, 100 },
+			want:     Violation_Rect,
+			repaired: func(m *Move) bool { return m.Left == 100 && m.Right == 200 },
+		},
+		{
+			name:     "rectangle outside the world",
+			move:     func(m *Move) { m.Action, m.Left, m.Right, m.Bottom = Action_AddToSelection, -10, 2000, 100 },
+			want:     Violation_Rect,
+			repaired: func(m *Move) bool { return m.Left == 0 && m.Right == g.WorldWidth },
+		},
+		{
+			name: "unknown vehicle type",
+			move: func(m *Move) { m.Action, m.Right, m.Bottom, m.Type = Action_Deselect, 100, 100, VehicleType(7) },
+			want: Violation_VehicleType,
+		},
+		{
+			name:       "empty selection",
+			unselected: true,
+			move:       func(m *Move) { m.Action, m.X = Action_Move, 10 },
+			want:       Violation_EmptySelection,
+		},
+		{
+			name:     "displacement",
+			move:     func(m *Move) { m.Action, m.X, m.Y = Action_Move, 2*g.WorldWidth, -2*g.WorldHeight },
+			want:     Violation_Displacement,
+			repaired: func(m *Move) bool { return m.X == g.WorldWidth && m.Y == -g.WorldHeight },
+		},
+		{
+			name:     "centre",
+			move:     func(m *Move) { m.Action, m.X, m.Y, m.Angle = Action_Rotate, -3*g.WorldWidth, 10, 1 },
+			want:     Violation_Centre,
+			repaired: func(m *Move) bool { return m.X == -g.WorldWidth && m.Y == 10 },
+		},
+		{
+			name:     "angle",
+			move:     func(m *Move) { m.Action, m.Angle = Action_Rotate, 3*math.Pi/2 },
+			want:     Violation_Angle,
+			repaired: func(m *Move) bool { return math.Abs(m.Angle+math.Pi/2) < 1e-9 },
+		},
+		{
+			name: "infinite angle",
+			move: func(m *Move) { m.Action, m.Angle = Action_Rotate, math.Inf(1) },
+			want: Violation_Angle,
+		},
+		{
+			name:     "factor",
+			move:     func(m *Move) { m.Action, m.Factor = Action_Scale, 20 },
+			want:     Violation_Factor,
+			repaired: func(m *Move) bool { return m.Factor == 10 },
+		},
+		{
+			name: "zero factor",
+			move: func(m *Move) { m.Action, m.Factor = Action_Scale, 0 },
+			want: Violation_Factor,
+		},
+		{
+			name:     "max speed",
+			move:     func(m *Move) { m.Action, m.X, m.MaxSpeed = Action_Move, 10, -1 },
+			want:     Violation_MaxSpeed,
+			repaired: func(m *Move) bool { return m.MaxSpeed == 0 },
+		},
+		{
+			name:     "max angular speed",
+			move:     func(m *Move) { m.Action, m.Angle, m.MaxAngularSpeed = Action_Rotate, 1, 4 },
+			want:     Violation_MaxAngularSpeed,
+			repaired: func(m *Move) bool { return m.MaxAngularSpeed == math.Pi },
+		},
+		{
+			name: "facility not owned",
+			move: func(m *Move) { m.Action, m.FacilityId, m.Type = Action_SetupVehicleProduction, 2, Vehicle_Ifv },
+			want: Violation_Facility,
+		},
+		{
+			name: "facility not a factory",
+			move: func(m *Move) { m.Action, m.FacilityId, m.Type = Action_SetupVehicleProduction, 3, Vehicle_Ifv },
+			want: Violation_Facility,
+		},
+		{
+			name:   "nuclear strike during cooldown",
+			player: func(p *Player) { p.RemainingNuclearStrikeCooldownTicks = 100 },
+			move:   func(m *Move) { m.Action, m.VehicleId, m.X, m.Y = Action_TacticalNuclearStrike, 1, 150, 100 },
+			want:   Violation_NuclearCooldown,
+		},
+		{
+			name:   "nuclear strike on its way",
+			player: func(p *Player) { p.NextNuclearStrikeTickIndex = 10 },
+			move:   func(m *Move) { m.Action, m.VehicleId, m.X, m.Y = Action_TacticalNuclearStrike, 1, 150, 100 },
+			want:   Violation_NuclearCooldown,
+		},
+		{
+			name: "enemy spotter",
+			move: func(m *Move) { m.Action, m.VehicleId, m.X, m.Y = Action_TacticalNuclearStrike, 2, 150, 100 },
+			want: Violation_Spotter,
+		},
+		{
+			name: "target out of sight",
+			move: func(m *Move) { m.Action, m.VehicleId, m.X, m.Y = Action_TacticalNuclearStrike, 1, 400, 100 },
+			want: Violation_Target,
+		},
+		{
+			name: "target outside the world",
+			move: func(m *Move) { m.Action, m.VehicleId, m.X, m.Y = Action_TacticalNuclearStrike, 1, -1, 100 },
+			want: Violation_Target,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, repair := range []bool{false, true} {
+			p, w := validatorWorld(g, !tt.unselected)
+			if tt.player != nil {
+				tt.player(p)
+			}
+			m := NewMove()
+			tt.move(m)
+			action := m.Action
+
+			v := NewMoveValidator(g)
+			var err error
+			if repair {
+				err = v.Repair(p, w, m)
+			} else {
+				err = v.Validate(p, w, m)
+			}
+
+			var errs MoveErrors
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Violation != tt.want || errs[0].Action != action {
+				t.Errorf("%s (repair %v): got %v, want only %v", tt.name, repair, err, tt.want)
+				continue
+			}
+
+			switch {
+			case !repair:
+				if errs[0].Repaired || m.Action != action {
+					t.Errorf("%s: Validate changed the move", tt.name)
+				}
+			case tt.repaired == nil:
+				if errs[0].Repaired || m.Action != Action_None {
+					t.Errorf("%s: unrepairable move left at %v, repaired %v", tt.name, m.Action, errs[0].Repaired)
+				}
+			default:
+				if !errs[0].Repaired || m.Action != action || !tt.repaired(m) {
+					t.Errorf("%s: repaired %v to %+v", tt.name, errs[0].Repaired, m)
+				}
+				if err := v.Validate(p, w, m); err != nil {
+					t.Errorf("%s: repaired move still fails with %v", tt.name, err)
+				}
+			}
+		}
+	}
+}
+
+func TestMoveValidatorAcceptsValidMoves(t *testing.T) {
+	g := DefaultGame(1)
+	p, w := validatorWorld(g, true)
+	v := NewMoveValidator(g)
+
+	moves := []func(m *Move){
+		func(m *Move) {},
+		func(m *Move) {
+			m.Action, m.Right, m.Bottom, m.Type = Action_ClearAndSelect, g.WorldWidth, g.WorldHeight, Vehicle_Tank
+		},
+		func(m *Move) { m.Action, m.Group = Action_Assign, g.MaxUnitGroup },
+		func(m *Move) { m.Action, m.X, m.Y, m.MaxSpeed = Action_Move, -g.WorldWidth, 10, 0.3 },
+		func(m *Move) {
+			m.Action, m.X, m.Y, m.Angle, m.MaxAngularSpeed = Action_Rotate, 2*g.WorldWidth, 0, -math.Pi, math.Pi
+		},
+		func(m *Move) { m.Action, m.Factor = Action_Scale, 0.1 },
+		func(m *Move) { m.Action, m.FacilityId, m.Type = Action_SetupVehicleProduction, 1, Vehicle_None },
+		func(m *Move) { m.Action, m.VehicleId, m.X, m.Y = Action_TacticalNuclearStrike, 1, 150, 100 },
+	}
+
+	for _, f := range moves {
+		m := NewMove()
+		f(m)
+		if err := v.Validate(p, w, m); err != nil {
+			t.Errorf("Validate(%v) = %v", m.Action, err)
+		}
+	}
+}
+
+func TestMoveErrorString(t *testing.T) {
+	e := &MoveError{Action: Action_Rotate, Violation: Violation_Angle}
+	if got, want := e.Error(), "move rotate: angle out of range"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	e.Repaired = true
+	if got, want := e.Error(), "move rotate: angle out of range (repaired)"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,299 @@
+package model
+
+import "sort"
+
+// SelectionTracker knows which of our vehicles are selected and plans the
+// moves that select an exact set of them. Call Update once per tick after
+// World.Vehicles is updated.
+type SelectionTracker struct {
+	mine     []*Vehicle
+	selected map[int64]bool
+}
+
+func NewSelectionTracker() *SelectionTracker {
+	return &SelectionTracker{selected: make(map[int64]bool)}
+}
+
+func (t *SelectionTracker) Update(w *World) {
+	t.mine = w.Vehicles.Mine()
+	t.selected = make(map[int64]bool)
+	for _, v := range t.mine {
+		if v.Selected {
+			t.selected[v.Id] = true
+		}
+	}
+}
+
+func (t *SelectionTracker) IsSelected(id int64) bool {
+	return t.selected[id]
+}
+
+// Selected returns the ids of the selected vehicles in order.
+func (t *SelectionTracker) Selected() []int64 {
+	ids := make([]int64, 0, len(t.selected))
+	for id := range t.selected {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (t *SelectionTracker) Len() int {
+	return len(t.selected)
+}
+
+// Plan returns the moves, to be sent one per tick, after which exactly the
+// given vehicles are selected. Unknown ids and ids of enemy vehicles are
+// ignored. The plan assumes the vehicles stand still until it is done.
+//
+// Plan is a heuristic: the plan ends with exactly the target selected, as long
+// as no two vehicles of the same type stand on the same point, but it isn't
+// guaranteed to be the shortest one. Three plans are compared and
+// the shortest one wins: selecting the target piece by piece, selecting its
+// bounding box and deselecting the intruders, and fixing up the current
+// selection. The pieces are whole groups first, then rectangles split in
+// halves until they are clean, which may take more moves than needed.
+func (t *SelectionTracker) Plan(target []int64) []*Move {
+	want := make(map[int64]bool)
+	mine := make(map[int64]*Vehicle, len(t.mine))
+	for _, v := range t.mine {
+		mine[v.Id] = v
+	}
+	for _, id := range target {
+		if mine[id] != nil {
+			want[id] = true
+		}
+	}
+
+	notWanted := func(v *Vehicle) bool { return !want[v.Id] }
+	wanted := func(v *Vehicle) bool { return want[v.Id] }
+
+	var missing, extra []*Vehicle
+	for _, v := range t.mine {
+		switch {
+		case want[v.Id] && !v.Selected:
+			missing = append(missing, v)
+		case !want[v.Id] && v.Selected:
+			extra = append(extra, v)
+		}
+	}
+	if len(missing) == 0 && len(extra) == 0 {
+		return nil
+	}
+
+	if len(want) == 0 {
+		return []*Move{t.selector(Action_Deselect, 0, t.bounds(t.mine), Vehicle_None)}
+	}
+
+	var targets []*Vehicle
+	for _, v := range t.mine {
+		if want[v.Id] {
+			targets = append(targets, v)
+		}
+	}
+
+	// Piece by piece: only target vehicles may be caught.
+	best := t.sequence(Action_ClearAndSelect, Action_AddToSelection, t.cover(targets, wanted))
+
+	// Bounding box minus intruders.
+	box := t.bounds(targets)
+	var intruders []*Vehicle
+	for _, v := range t.mine {
+		if !want[v.Id] && box.contains(v) {
+			intruders = append(intruders, v)
+		}
+	}
+	plan := []*Move{t.selector(Action_ClearAndSelect, 0, box, Vehicle_None)}
+	plan = append(plan, t.sequence(Action_Deselect, Action_Deselect, t.cover(intruders, notWanted))...)
+	if len(plan) < len(best) {
+		best = plan
+	}
+
+	// Fixing up the current selection: adding a vehicle that is already
+	// selected changes nothing, so it may be caught when adding.
+	if len(t.selected) > 0 {
+		plan = t.sequence(Action_AddToSelection, Action_AddToSelection,
+			t.cover(missing, func(v *Vehicle) bool { return want[v.Id] || v.Selected }))
+		plan = append(plan, t.sequence(Action_Deselect, Action_Deselect, t.cover(extra, notWanted))...)
+		if len(plan) < len(best) {
+			best = plan
+		}
+	}
+
+	return best
+}
+
+// piece is a single selection: a group, or a rectangle with an optional type.
+type piece struct {
+	group int
+	rect  rect
+	t     VehicleType
+}
+
+type rect struct {
+	left, top, right, bottom float64
+}
+
+func (r rect) contains(v *Vehicle) bool {
+	return v.X >= r.left && v.X <= r.right && v.Y >= r.top && v.Y <= r.bottom
+}
+
+func (t *SelectionTracker) bounds(vehicles []*Vehicle) rect {
+	r := rect{vehicles[0].X, vehicles[0].Y, vehicles[0].X, vehicles[0].Y}
+	for _, v := range vehicles[1:] {
+		if v.X < r.left {
+			r.left = v.X
+		}
+		if v.X > r.right {
+			r.right = v.X
+		}
+		if v.Y < r.top {
+			r.top = v.Y
+		}
+		if v.Y > r.bottom {
+			r.bottom = v.Y
+		}
+	}
+	return r
+}
+
+// cover returns pieces that together catch every vehicle of vehicles and only
+// vehicles for which allowed holds. Whole groups are used first, then
+// rectangles.
+func (t *SelectionTracker) cover(vehicles []*Vehicle, allowed func(*Vehicle) bool) []piece {
+	if len(vehicles) == 0 {
+		return nil
+	}
+
+	members := make(map[int][]*Vehicle)
+	for _, v := range t.mine {
+		for _, g := range v.Groups {
+			members[g] = append(members[g], v)
+		}
+	}
+
+	left := make(map[int64]bool, len(vehicles))
+	for _, v := range vehicles {
+		left[v.Id] = true
+	}
+
+	var groups []int
+	for g, vs := range members {
+		clean, useful := true, false
+		for _, v := range vs {
+			clean = clean && allowed(v)
+			useful = useful || left[v.Id]
+		}
+		if clean && useful {
+			groups = append(groups, g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(members[groups[i]]) != len(members[groups[j]]) {
+			return len(members[groups[i]]) > len(members[groups[j]])
+		}
+		return groups[i] < groups[j]
+	})
+
+	var pieces []piece
+	for _, g := range groups {
+		useful := false
+		for _, v := range members[g] {
+			useful = useful || left[v.Id]
+		}
+		if !useful {
+			continue
+		}
+		pieces = append(pieces, piece{group: g})
+		for _, v := range members[g] {
+			delete(left, v.Id)
+		}
+	}
+
+	var rest []*Vehicle
+	for _, v := range vehicles {
+		if left[v.Id] {
+			rest = append(rest, v)
+		}
+	}
+	return append(pieces, t.rects(rest, allowed)...)
+}
+
+// rects covers the vehicles with clean rectangles, splitting them by type and
+// then in halves along the longer side until the rectangles are clean.
+func (t *SelectionTracker) rects(vehicles []*Vehicle, allowed func(*Vehicle) bool) []piece {
+	if len(vehicles) == 0 {
+		return nil
+	}
+
+	r := t.bounds(vehicles)
+	if t.clean(r, Vehicle_None, allowed) {
+		return []piece{{rect: r, t: Vehicle_None}}
+	}
+
+	byType := make(map[VehicleType][]*Vehicle)
+	for _, v := range vehicles {
+		byType[v.Type] = append(byType[v.Type], v)
+	}
+
+	if len(byType) > 1 {
+		var pieces []piece
+		for _, vt := range []VehicleType{Vehicle_Arrv, Vehicle_Fighter, Vehicle_Helicopter, Vehicle_Ifv, Vehicle_Tank} {
+			pieces = append(pieces, t.rects(byType[vt], allowed)...)
+		}
+		return pieces
+	}
+
+	vt := vehicles[0].Type
+	if t.clean(r, vt, allowed) {
+		return []piece{{rect: r, t: vt}}
+	}
+	if len(vehicles) == 1 {
+		// Another vehicle of the same type stands exactly here.
+		return []piece{{rect: r, t: vt}}
+	}
+
+	sorted := append([]*Vehicle(nil), vehicles...)
+	if r.right-r.left >= r.bottom-r.top {
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].X < sorted[j].X })
+	} else {
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Y < sorted[j].Y })
+	}
+	half := len(sorted) / 2
+	return append(t.rects(sorted[:half], allowed), t.rects(sorted[half:], allowed)...)
+}
+
+// clean reports whether the rectangle catches only allowed vehicles.
+func (t *SelectionTracker) clean(r rect, vt VehicleType, allowed func(*Vehicle) bool) bool {
+	for _, v := range t.mine {
+		if (vt == Vehicle_None || v.Type == vt) && r.contains(v) && !allowed(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// sequence turns the pieces into moves, the first one with the action first
+// and the others with the action rest.
+func (t *SelectionTracker) sequence(first, rest ActionType, pieces []piece) []*Move {
+	moves := make([]*Move, 0, len(pieces))
+	for i, p := range pieces {
+		action := rest
+		if i == 0 {
+			action = first
+		}
+		moves = append(moves, t.selector(action, p.group, p.rect, p.t))
+	}
+	return moves
+}
+
+func (t *SelectionTracker) selector(action ActionType, group int, r rect, vt VehicleType) *Move {
+	m := NewMove()
+	m.Action = action
+	m.Group = group
+	if group == 0 {
+		m.Left, m.Top, m.Right, m.Bottom = r.left, r.top, r.right, r.bottom
+		m.Type = vt
+	}
+	return m
+}
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// applySelection performs the selection moves on the vehicles like the
+// server does.
+func applySelection(vehicles []*Vehicle, moves []*Move) {
+	for _, m := range moves {
+		for _, v := range vehicles {
+			caught := v.InGroup(m.Group)
+			if m.Group == 0 {
+				caught = (m.Type == Vehicle_None || v.Type == m.Type) &&
+					v.X >= m.Left && v.X <= m.Right && v.Y >= m.Top && v.Y <= m.Bottom
+			}
+
+			switch m.Action {
+			case Action_ClearAndSelect:
+				v.Selected = caught
+			case Action_AddToSelection:
+				v.Selected = v.Selected || caught
+			case Action_Deselect:
+				v.Selected = v.Selected && !caught
+			}
+		}
+	}
+}
+
+func selectionWorld(vehicles []*Vehicle) *World {
+	w := &World{Players: []*Player{{Id: 1, Me: true}, {Id: 2}}, NewVehicles: vehicles, Vehicles: NewVehicleRegistry()}
+	w.Vehicles.Update(w)
+	return w
+}
+
+// planSelection plans the selection of target and returns the plan and the
+// ids selected once it is performed.
+func planSelection(vehicles []*Vehicle, target []int64) ([]*Move, []int64) {
+	w := selectionWorld(vehicles)
+	tracker := NewSelectionTracker()
+	tracker.Update(w)
+	plan := tracker.Plan(target)
+
+	mine := w.Vehicles.Mine()
+	applySelection(mine, plan)
+	var selected []int64
+	for _, v := range mine {
+		if v.Selected {
+			selected = append(selected, v.Id)
+		}
+	}
+	return plan, selected
+}
+
+func selectionVehicle(id int64, t VehicleType, x, y float64, groups ...int) *Vehicle {
+	return &Vehicle{Unit: Unit{Id: id, X: x, Y: y}, PlayerId: 1, Type: t, Groups: groups}
+}
+
+func TestSelectionPlan(t *testing.T) {
+	// Two rows of tanks with an IFV in the middle of the first row.
+	var vehicles []*Vehicle
+	for i := 0; i < 5; i++ {
+		vt := Vehicle_Tank
+		if i == 2 {
+			vt = Vehicle_Ifv
+		}
+		vehicles = append(vehicles, selectionVehicle(int64(i+1), vt, float64(10*i), 10, 1))
+		vehicles = append(vehicles, selectionVehicle(int64(i+11), Vehicle_Tank, float64(10*i), 20))
+	}
+	vehicles[0].Selected = true
+
+	tests := []struct {
+		name   string
+		target []int64
+		moves  int
+	}{
+		{"selected already", []int64{1}, 0},
+		{"nothing", nil, 1},
+		{"a group", []int64{1, 2, 3, 4, 5}, 1},
+		{"a clean rectangle", []int64{11, 12, 13}, 1},
+		{"a type inside a rectangle", []int64{1, 2, 4, 5, 11, 12, 13, 14, 15}, 1},
+		{"a box with intruders", []int64{1, 2, 4, 5, 11, 12, 14, 15}, 2},
+		{"added to the selection", []int64{1, 11}, 1},
+		{"unknown ids", []int64{1, 99}, 0},
+	}
+
+	for _, tt := range tests {
+		plan, selected := planSelection(cloneVehicles(vehicles), tt.target)
+		if want := knownIds(vehicles, tt.target); !sameIdSet(selected, want) {
+			t.Errorf("%s: selected %v, want %v", tt.name, selected, want)
+		}
+		if len(plan) != tt.moves {
+			t.Errorf("%s: %d moves, want %d", tt.name, len(plan), tt.moves)
+		}
+	}
+}
+
+// TestSelectionPlanRandom checks that any plan, short or not, selects
+// exactly the target.
+func TestSelectionPlanRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	types := []VehicleType{Vehicle_Arrv, Vehicle_Fighter, Vehicle_Helicopter, Vehicle_Ifv, Vehicle_Tank}
+
+	for round := 0; round < 200; round++ {
+		var vehicles []*Vehicle
+		var target []int64
+		// Coarse positions so that vehicles share rows and columns, but no
+		// two stand on the same point.
+		cells := rnd.Perm(64)
+		for id := int64(1); id <= 40; id++ {
+			cell := cells[id-1]
+			v := selectionVehicle(id, types[rnd.Intn(len(types))], float64(cell%8*10), float64(cell/8*10))
+			if rnd.Intn(3) == 0 {
+				v.Groups = []int{1 + rnd.Intn(3)}
+			}
+			v.Selected = rnd.Intn(4) == 0
+			vehicles = append(vehicles, v)
+			if rnd.Intn(3) == 0 {
+				target = append(target, id)
+			}
+		}
+
+		plan, selected := planSelection(vehicles, target)
+		if !sameIdSet(selected, target) {
+			t.Fatalf("round %d: %d moves selected %v, want %v", round, len(plan), selected, target)
+		}
+	}
+}
+
+func cloneVehicles(vehicles []*Vehicle) []*Vehicle {
+	clones := make([]*Vehicle, len(vehicles))
+	for i, v := range vehicles {
+		c := *v
+		clones[i] = &c
+	}
+	return clones
+}
+
+func knownIds(vehicles []*Vehicle, ids []int64) []int64 {
+	known := make(map[int64]bool)
+	for _, v := range vehicles {
+		known[v.Id] = true
+	}
+	var result []int64
+	for _, id := range ids {
+		if known[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func sameIdSet(a, b []int64) bool {
+	a, b = append([]int64(nil), a...), append([]int64(nil), b...)
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+	return equalIds(a, b)
+}
@@ -0,0 +1,237 @@
+package model
+
+import (
+	"math"
+	"sort"
+)
+
+// SpatialIndex buckets vehicles into a uniform grid so that neighbourhood
+// queries only look at the cells around the point instead of every vehicle.
+type SpatialIndex struct {
+	cellSize   float64
+	cols, rows int
+	cells      [][]*Vehicle
+	cellOf     map[int64]int
+}
+
+// NewSpatialIndex creates an empty index over a world of the given size.
+// Cells about as large as the typical query radius work best.
+func NewSpatialIndex(width, height, cellSize float64) *SpatialIndex {
+	x := &SpatialIndex{
+		cellSize: cellSize,
+		cols:     int(math.Ceil(width/cellSize)) + 1,
+		rows:     int(math.Ceil(height/cellSize)) + 1,
+		cellOf:   make(map[int64]int),
+	}
+	x.cells = make([][]*Vehicle, x.cols*x.rows)
+	return x
+}
+
+// Build replaces the content of the index with the given vehicles.
+func (x *SpatialIndex) Build(vehicles []*Vehicle) {
+	for i := range x.cells {
+		x.cells[i] = x.cells[i][:0]
+	}
+	x.cellOf = make(map[int64]int, len(vehicles))
+
+	for _, v := range vehicles {
+		x.Insert(v)
+	}
+}
+
+// Update applies the deltas of the tick to the vehicles of w.Vehicles, which
+// must already be updated. The index then holds the registry's vehicles.
+func (x *SpatialIndex) Update(w *World) {
+	if w.Vehicles == nil {
+		return
+	}
+
+	for _, v := range w.NewVehicles {
+		if rv := w.Vehicles.Get(v.Id); rv != nil {
+			x.Remove(v.Id)
+			x.Insert(rv)
+		}
+	}
+
+	for _, u := range w.VehicleUpdates {
+		if rv := w.Vehicles.Get(u.Id); rv != nil {
+			x.Move(rv)
+		} else {
+			x.Remove(u.Id)
+		}
+	}
+}
+
+func (x *SpatialIndex) Len() int {
+	return len(x.cellOf)
+}
+
+func (x *SpatialIndex) cell(px, py float64) (int, int) {
+	return clampCell(int(px/x.cellSize), x.cols), clampCell(int(py/x.cellSize), x.rows)
+}
+
+func (x *SpatialIndex) index(px, py float64) int {
+	cx, cy := x.cell(px, py)
+	return cy*x.cols + cx
+}
+
+// Insert adds the vehicle at its current position.
+func (x *SpatialIndex) Insert(v *Vehicle) {
+	i := x.index(v.X, v.Y)
+	x.cells[i] = append(x.cells[i], v)
+	x.cellOf[v.Id] = i
+}
+
+// Remove drops the vehicle with the given id if it is indexed.
+func (x *SpatialIndex) Remove(id int64) {
+	i, ok := x.cellOf[id]
+	if !ok {
+		return
+	}
+	delete(x.cellOf, id)
+
+	cell := x.cells[i]
+	for j, v := range cell {
+		if v.Id == id {
+			x.cells[i] = append(cell[:j], cell[j+1:]...)
+			return
+		}
+	}
+}
+
+// Move updates the cell of a vehicle whose position changed, inserting it if
+// it isn't indexed yet.
+func (x *SpatialIndex) Move(v *Vehicle) {
+	i, ok := x.cellOf[v.Id]
+	if ok && i == x.index(v.X, v.Y) {
+		return
+	}
+	x.Remove(v.Id)
+	x.Insert(v)
+}
+
+// visit calls f for the vehicles of the cells overlapping the rectangle.
+func (x *SpatialIndex) visit(left, top, right, bottom float64, f func(*Vehicle)) {
+	x0, y0 := x.cell(left, top)
+	x1, y1 := x.cell(right, bottom)
+
+	for cy := y0; cy <= y1; cy++ {
+		for cx := x0; cx <= x1; cx++ {
+			for _, v := range x.cells[cy*x.cols+cx] {
+				f(v)
+			}
+		}
+	}
+}
+
+// Radius returns the vehicles whose centre is within r of the point and that
+// match f. A nil f matches all.
+func (x *SpatialIndex) Radius(px, py, r float64, f func(*Vehicle) bool) []*Vehicle {
+	var result []*Vehicle
+	x.visit(px-r, py-r, px+r, py+r, func(v *Vehicle) {
+		if v.GetSquaredDistanceTo(px, py) <= r*r && (f == nil || f(v)) {
+			result = append(result, v)
+		}
+	})
+	return result
+}
+
+// Rect returns the vehicles whose centre lies inside the rectangle and that
+// match f, using the same bounds as a selection.
+func (x *SpatialIndex) Rect(left, top, right, bottom float64, f func(*Vehicle) bool) []*Vehicle {
+	var result []*Vehicle
+	x.visit(left, top, right, bottom, func(v *Vehicle) {
+		if v.X >= left && v.X <= right && v.Y >= top && v.Y <= bottom && (f == nil || f(v)) {
+			result = append(result, v)
+		}
+	})
+	return result
+}
+
+// Nearest returns up to k vehicles matching f, closest to the point first.
+func (x *SpatialIndex) Nearest(px, py float64, k int, f func(*Vehicle) bool) []*Vehicle {
+	if k <= 0 {
+		return nil
+	}
+
+	type candidate struct {
+		v *Vehicle
+		d float64
+	}
+	var best []candidate
+
+	cx, cy := x.cell(px, py)
+	maxRing := x.cols
+	if x.rows > maxRing {
+		maxRing = x.rows
+	}
+
+	for ring := 0; ring <= maxRing; ring++ {
+		for dy := -ring; dy <= ring; dy++ {
+			for dx := -ring; dx <= ring; dx++ {
+				if dx != -ring && dx != ring && dy != -ring && dy != ring {
+					continue
+				}
+				gx, gy := cx+dx, cy+dy
+				if gx < 0 || gx >= x.cols || gy < 0 || gy >= x.rows {
+					continue
+				}
+
+				for _, v := range x.cells[gy*x.cols+gx] {
+					if f != nil && !f(v) {
+						continue
+					}
+					d := v.GetSquaredDistanceTo(px, py)
+					if len(best) == k && d >= best[k-1].d {
+						continue
+					}
+					i := sort.Search(len(best), func(i int) bool { return best[i].d > d })
+					if len(best) < k {
+						best = append(best, candidate{})
+					}
+					copy(best[i+1:], best[i:])
+					best[i] = candidate{v, d}
+				}
+			}
+		}
+
+		// Every vehicle outside of the rings visited so far is at least
+		// ring cells away.
+		if r := float64(ring) * x.cellSize; len(best) == k && best[k-1].d <= r*r {
+			break
+		}
+	}
+
+	result := make([]*Vehicle, len(best))
+	for i, c := range best {
+		result[i] = c.v
+	}
+	return result
+}
+
+// OfPlayer matches the vehicles of the player.
+func OfPlayer(playerId int64) func(*Vehicle) bool {
+	return func(v *Vehicle) bool { return v.PlayerId == playerId }
+}
+
+// NotOfPlayer matches the vehicles of everyone but the player.
+func NotOfPlayer(playerId int64) func(*Vehicle) bool {
+	return func(v *Vehicle) bool { return v.PlayerId != playerId }
+}
+
+// OfType matches the vehicles of the given type.
+func OfType(t VehicleType) func(*Vehicle) bool {
+	return func(v *Vehicle) bool { return v.Type == t }
+}
+
+// MatchAll matches the vehicles matched by every filter.
+func MatchAll(filters ...func(*Vehicle) bool) func(*Vehicle) bool {
+	return func(v *Vehicle) bool {
+		for _, f := range filters {
+			if !f(v) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,251 @@
+package model
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+const testWorldSize = 1024
+
+// testVehicles returns n vehicles of each of two players spread over the
+// world.
+func testVehicles(rnd *rand.Rand, n int) []*Vehicle {
+	var vehicles []*Vehicle
+	for i := 0; i < 2*n; i++ {
+		v := &Vehicle{PlayerId: int64(1 + i%2), Durability: 100, Type: VehicleType(i % 5)}
+		v.Id = int64(i + 1)
+		v.X, v.Y = rnd.Float64()*testWorldSize, rnd.Float64()*testWorldSize
+		vehicles = append(vehicles, v)
+	}
+	return vehicles
+}
+
+func bruteRadius(vehicles []*Vehicle, px, py, r float64, f func(*Vehicle) bool) []*Vehicle {
+	var result []*Vehicle
+	for _, v := range vehicles {
+		if v.GetSquaredDistanceTo(px, py) <= r*r && (f == nil || f(v)) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func bruteRect(vehicles []*Vehicle, left, top, right, bottom float64) []*Vehicle {
+	var result []*Vehicle
+	for _, v := range vehicles {
+		if v.X >= left && v.X <= right && v.Y >= top && v.Y <= bottom {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// bruteNearest keeps the k closest vehicles seen so far while going through
+// every vehicle.
+func bruteNearest(vehicles []*Vehicle, px, py float64, k int, f func(*Vehicle) bool) []*Vehicle {
+	var result []*Vehicle
+	for _, v := range vehicles {
+		if f != nil && !f(v) {
+			continue
+		}
+		d := v.GetSquaredDistanceTo(px, py)
+		if len(result) == k && d >= result[k-1].GetSquaredDistanceTo(px, py) {
+			continue
+		}
+		i := sort.Search(len(result), func(i int) bool { return result[i].GetSquaredDistanceTo(px, py) > d })
+		if len(result) < k {
+			result = append(result, nil)
+		}
+		copy(result[i+1:], result[i:])
+		result[i] = v
+	}
+	return result
+}
+
+func sameIds(a, b []*Vehicle) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ids := make(map[int64]int)
+	for _, v := range a {
+		ids[v.Id]++
+	}
+	for _, v := range b {
+		ids[v.Id]--
+	}
+	for _, n := range ids {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sameDistances compares nearest results by distance, which tolerates ties
+// listed in a different order.
+func sameDistances(a, b []*Vehicle, px, py float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].GetSquaredDistanceTo(px, py) != b[i].GetSquaredDistanceTo(px, py) {
+			return false
+		}
+	}
+	return true
+}
+
+// testPoints are query points inside the world, on its edges and outside it,
+// where the index clamps them to the border cells.
+var testPoints = [][2]float64{
+	{512, 512}, {0, 0}, {testWorldSize, testWorldSize}, {3, 1000},
+	{-50, 300}, {300, -50}, {-400, -400}, {1500, 200}, {200, 2000}, {5000, 5000},
+}
+
+func checkIndex(t *testing.T, index *SpatialIndex, vehicles []*Vehicle) {
+	t.Helper()
+
+	if index.Len() != len(vehicles) {
+		t.Errorf("Len() = %d, want %d", index.Len(), len(vehicles))
+	}
+
+	enemy := NotOfPlayer(1)
+	for _, p := range testPoints {
+		px, py := p[0], p[1]
+
+		for _, r := range []float64{0, 10, 64, 300, 2000} {
+			if got, want := index.Radius(px, py, r, nil), bruteRadius(vehicles, px, py, r, nil); !sameIds(got, want) {
+				t.Errorf("Radius(%v, %v, %v) found %d vehicles, want %d", px, py, r, len(got), len(want))
+			}
+			if got, want := index.Radius(px, py, r, enemy), bruteRadius(vehicles, px, py, r, enemy); !sameIds(got, want) {
+				t.Errorf("Radius(%v, %v, %v, enemy) found %d vehicles, want %d", px, py, r, len(got), len(want))
+			}
+		}
+
+		for _, size := range []float64{0, 50, 400} {
+			left, top, right, bottom := px-size, py-size, px+size, py+size
+			if got, want := index.Rect(left, top, right, bottom, nil), bruteRect(vehicles, left, top, right, bottom); !sameIds(got, want) {
+				t.Errorf("Rect(%v, %v, %v, %v) found %d vehicles, want %d", left, top, right, bottom, len(got), len(want))
+			}
+		}
+
+		for _, k := range []int{1, 5, 50, len(vehicles) + 1} {
+			if got, want := index.Nearest(px, py, k, nil), bruteNearest(vehicles, px, py, k, nil); !sameDistances(got, want, px, py) {
+				t.Errorf("Nearest(%v, %v, %d) found %d vehicles, want %d", px, py, k, len(got), len(want))
+			}
+			if got, want := index.Nearest(px, py, k, enemy), bruteNearest(vehicles, px, py, k, enemy); !sameDistances(got, want, px, py) {
+				t.Errorf("Nearest(%v, %v, %d, enemy) found %d vehicles, want %d", px, py, k, len(got), len(want))
+			}
+		}
+	}
+}
+
+func TestSpatialIndexMatchesBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	vehicles := testVehicles(rnd, 500)
+
+	index := NewSpatialIndex(testWorldSize, testWorldSize, 32)
+	index.Build(vehicles)
+	checkIndex(t, index, vehicles)
+}
+
+func TestSpatialIndexUpdate(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	vehicles := testVehicles(rnd, 500)
+
+	registry := NewVehicleRegistry()
+	index := NewSpatialIndex(testWorldSize, testWorldSize, 32)
+	w := &World{Players: []*Player{{Id: 1, Me: true}, {Id: 2}}, Vehicles: registry, NewVehicles: vehicles}
+	registry.Update(w)
+	index.Update(w)
+	checkIndex(t, index, registry.All())
+
+	for tick := 0; tick < 5; tick++ {
+		w.NewVehicles = nil
+		w.VehicleUpdates = nil
+		for _, v := range registry.All() {
+			u := &VehicleUpdate{Id: v.Id, X: v.X, Y: v.Y, Durability: v.Durability}
+			switch n := rnd.Intn(10); {
+			case n == 0:
+				// Destroyed, or an enemy gone into the fog of war: both
+				// are sent with a durability of zero.
+				u.Durability = 0
+			case n < 6:
+				u.X = clampTest(v.X + rnd.NormFloat64()*40)
+				u.Y = clampTest(v.Y + rnd.NormFloat64()*40)
+			default:
+				continue
+			}
+			w.VehicleUpdates = append(w.VehicleUpdates, u)
+		}
+
+		// An update of a vehicle the registry doesn't know is ignored.
+		w.VehicleUpdates = append(w.VehicleUpdates, &VehicleUpdate{Id: 1 << 40, X: 1, Y: 1})
+
+		registry.Update(w)
+		index.Update(w)
+		checkIndex(t, index, registry.All())
+	}
+}
+
+func clampTest(x float64) float64 {
+	if x < 0 {
+		return 0
+	}
+	if x > testWorldSize {
+		return testWorldSize
+	}
+	return x
+}
+
+func benchmarkVehicles() []*Vehicle {
+	return testVehicles(rand.New(rand.NewSource(3)), 500)
+}
+
+func BenchmarkSpatialIndexRadius(b *testing.B) {
+	vehicles := benchmarkVehicles()
+	index := NewSpatialIndex(testWorldSize, testWorldSize, 32)
+	index.Build(vehicles)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := vehicles[i%len(vehicles)]
+		index.Radius(v.X, v.Y, 50, nil)
+	}
+}
+
+func BenchmarkSpatialIndexNearest(b *testing.B) {
+	vehicles := benchmarkVehicles()
+	index := NewSpatialIndex(testWorldSize, testWorldSize, 32)
+	index.Build(vehicles)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := vehicles[i%len(vehicles)]
+		index.Nearest(v.X, v.Y, 5, NotOfPlayer(v.PlayerId))
+	}
+}
+
+// BenchmarkBruteForceRadius is the baseline of BenchmarkSpatialIndexRadius:
+// the distance to every vehicle.
+func BenchmarkBruteForceRadius(b *testing.B) {
+	vehicles := benchmarkVehicles()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := vehicles[i%len(vehicles)]
+		bruteRadius(vehicles, v.X, v.Y, 50, nil)
+	}
+}
+
+// BenchmarkBruteForceNearest is the baseline of BenchmarkSpatialIndexNearest.
+func BenchmarkBruteForceNearest(b *testing.B) {
+	vehicles := benchmarkVehicles()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := vehicles[i%len(vehicles)]
+		bruteNearest(vehicles, v.X, v.Y, 5, NotOfPlayer(v.PlayerId))
+	}
+}
@@ -0,0 +1,170 @@
+package model
+
+// TerrainMap answers terrain and weather questions by world coordinates. The
+// runner sends the grids only on tick 0; the map keeps them for the rest of
+// the game and caches the multipliers of every cell.
+type TerrainMap struct {
+	game *Game
+
+	width, height float64
+	cols, rows    int
+
+	terrain [][]Terrain
+	weather [][]Weather
+
+	// ground and aerial hold the factors of every cell, indexed by
+	// y*cols + x.
+	ground []cellFactors
+	aerial []cellFactors
+}
+
+type cellFactors struct {
+	vision, stealth, speed float64
+}
+
+func NewTerrainMap(g *Game) *TerrainMap {
+	return &TerrainMap{
+		game:   g,
+		width:  g.WorldWidth,
+		height: g.WorldHeight,
+		cols:   g.TerrainWeatherMapColumnCount,
+		rows:   g.TerrainWeatherMapRowCount,
+	}
+}
+
+// Update takes the grids of the tick if it has them and otherwise puts the
+// remembered ones back into w, so they stay available after tick 0.
+func (m *TerrainMap) Update(w *World) {
+	if w.Width > 0 && w.Height > 0 {
+		m.width, m.height = w.Width, w.Height
+	}
+
+	if len(w.TerrainByCellXY) == 0 || len(w.WeatherByCellXY) == 0 {
+		w.TerrainByCellXY = m.terrain
+		w.WeatherByCellXY = m.weather
+		return
+	}
+	if m.Known() && &w.TerrainByCellXY[0] == &m.terrain[0] && &w.WeatherByCellXY[0] == &m.weather[0] {
+		return
+	}
+
+	m.terrain, m.weather = w.TerrainByCellXY, w.WeatherByCellXY
+	m.cols, m.rows = len(m.terrain), len(m.terrain[0])
+	if m.rows == 0 {
+		m.rows = 1
+	}
+
+	m.ground = make([]cellFactors, m.cols*m.rows)
+	m.aerial = make([]cellFactors, m.cols*m.rows)
+	for x := 0; x < m.cols; x++ {
+		for y := 0; y < m.rows; y++ {
+			i := y*m.cols + x
+			g, a := &m.ground[i], &m.aerial[i]
+			if y < len(m.terrain[x]) {
+				g.vision, g.stealth, g.speed = m.game.TerrainFactors(m.terrain[x][y])
+			} else {
+				g.vision, g.stealth, g.speed = m.game.TerrainFactors(Terrain_Plain)
+			}
+			if x < len(m.weather) && y < len(m.weather[x]) {
+				a.vision, a.stealth, a.speed = m.game.WeatherFactors(m.weather[x][y])
+			} else {
+				a.vision, a.stealth, a.speed = m.game.WeatherFactors(Weather_Clear)
+			}
+		}
+	}
+}
+
+// Known reports whether the grids have been received.
+func (m *TerrainMap) Known() bool {
+	return m.ground != nil
+}
+
+// Size returns the number of columns and rows of the grid.
+func (m *TerrainMap) Size() (cols, rows int) {
+	return m.cols, m.rows
+}
+
+// CellSize returns the width and height of a grid cell.
+func (m *TerrainMap) CellSize() (width, height float64) {
+	if m.cols <= 0 || m.rows <= 0 {
+		return m.width, m.height
+	}
+	return m.width / float64(m.cols), m.height / float64(m.rows)
+}
+
+// CellFactors is Factors for the cell (cx, cy).
+func (m *TerrainMap) CellFactors(cx, cy int, aerial bool) (vision, stealth, speed float64) {
+	w, h := m.CellSize()
+	return m.Factors((float64(cx)+0.5)*w, (float64(cy)+0.5)*h, aerial)
+}
+
+// Cell returns the grid cell containing the point. Points outside of the
+// world are mapped to the closest cell.
+func (m *TerrainMap) Cell(x, y float64) (cx, cy int) {
+	if m.cols <= 0 || m.rows <= 0 || m.width <= 0 || m.height <= 0 {
+		return 0, 0
+	}
+
+	cx = int(x / m.width * float64(m.cols))
+	cy = int(y / m.height * float64(m.rows))
+	return clampCell(cx, m.cols), clampCell(cy, m.rows)
+}
+
+func clampCell(c, n int) int {
+	if c < 0 {
+		return 0
+	}
+	if c >= n {
+		return n - 1
+	}
+	return c
+}
+
+// Terrain returns the terrain at the point, Terrain_Plain if the grid is
+// unknown.
+func (m *TerrainMap) Terrain(x, y float64) Terrain {
+	if !m.Known() {
+		return Terrain_Plain
+	}
+	cx, cy := m.Cell(x, y)
+	if cy >= len(m.terrain[cx]) {
+		return Terrain_Plain
+	}
+	return m.terrain[cx][cy]
+}
+
+// Weather returns the weather at the point, Weather_Clear if the grid is
+// unknown.
+func (m *TerrainMap) Weather(x, y float64) Weather {
+	if !m.Known() {
+		return Weather_Clear
+	}
+	cx, cy := m.Cell(x, y)
+	if cx >= len(m.weather) || cy >= len(m.weather[cx]) {
+		return Weather_Clear
+	}
+	return m.weather[cx][cy]
+}
+
+// Factors returns the vision, stealth and speed multipliers for a vehicle at
+// the point: terrain applies to ground vehicles, weather to aerial ones.
+func (m *TerrainMap) Factors(x, y float64, aerial bool) (vision, stealth, speed float64) {
+	if !m.Known() {
+		if aerial {
+			return m.game.WeatherFactors(Weather_Clear)
+		}
+		return m.game.TerrainFactors(Terrain_Plain)
+	}
+
+	cx, cy := m.Cell(x, y)
+	f := &m.ground[cy*m.cols+cx]
+	if aerial {
+		f = &m.aerial[cy*m.cols+cx]
+	}
+	return f.vision, f.stealth, f.speed
+}
+
+// VehicleFactors returns the multipliers for the vehicle at its position.
+func (m *TerrainMap) VehicleFactors(v *Vehicle) (vision, stealth, speed float64) {
+	return m.Factors(v.X, v.Y, v.Aerial)
+}
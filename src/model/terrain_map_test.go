@@ -0,0 +1,119 @@
+package model
+
+import "testing"
+
+// terrainGrids returns 4 by 4 grids with the terrain and weather changing
+// along the columns and the rows.
+func terrainGrids() ([][]Terrain, [][]Weather) {
+	terrain := make([][]Terrain, 4)
+	weather := make([][]Weather, 4)
+	for x := range terrain {
+		terrain[x] = make([]Terrain, 4)
+		weather[x] = make([]Weather, 4)
+		for y := range terrain[x] {
+			terrain[x][y] = Terrain(x % 3)
+			weather[x][y] = Weather(y % 3)
+		}
+	}
+	return terrain, weather
+}
+
+func TestTerrainMapUnknown(t *testing.T) {
+	g := DefaultGame(1)
+	m := NewTerrainMap(g)
+
+	if m.Known() || m.Terrain(100, 100) != Terrain_Plain || m.Weather(100, 100) != Weather_Clear {
+		t.Errorf("unknown map: known %v, terrain %v, weather %v", m.Known(), m.Terrain(100, 100), m.Weather(100, 100))
+	}
+	if v, s, sp := m.Factors(100, 100, false); v != g.PlainTerrainVisionFactor || s != g.PlainTerrainStealthFactor || sp != g.PlainTerrainSpeedFactor {
+		t.Errorf("unknown map: ground factors %v %v %v, want the plain ones", v, s, sp)
+	}
+	if v, s, sp := m.Factors(100, 100, true); v != g.ClearWeatherVisionFactor || s != g.ClearWeatherStealthFactor || sp != g.ClearWeatherSpeedFactor {
+		t.Errorf("unknown map: aerial factors %v %v %v, want the clear ones", v, s, sp)
+	}
+}
+
+func TestTerrainMapFactors(t *testing.T) {
+	g := DefaultGame(1)
+	terrain, weather := terrainGrids()
+	m := NewTerrainMap(g)
+	m.Update(&World{Width: 400, Height: 400, TerrainByCellXY: terrain, WeatherByCellXY: weather})
+
+	if cols, rows := m.Size(); cols != 4 || rows != 4 {
+		t.Fatalf("Size() = %d, %d, want 4, 4", cols, rows)
+	}
+	if w, h := m.CellSize(); w != 100 || h != 100 {
+		t.Errorf("CellSize() = %v, %v, want 100, 100", w, h)
+	}
+
+	// Every cell has the factors of its own terrain and weather.
+	for cx := 0; cx < 4; cx++ {
+		for cy := 0; cy < 4; cy++ {
+			x, y := float64(cx*100+50), float64(cy*100+50)
+			if got := m.Terrain(x, y); got != terrain[cx][cy] {
+				t.Errorf("cell %d, %d: terrain %v, want %v", cx, cy, got, terrain[cx][cy])
+			}
+
+			gv, gs, gsp := m.Factors(x, y, false)
+			wv, ws, wsp := g.TerrainFactors(terrain[cx][cy])
+			if gv != wv || gs != ws || gsp != wsp {
+				t.Errorf("cell %d, %d: ground factors %v %v %v, want %v %v %v", cx, cy, gv, gs, gsp, wv, ws, wsp)
+			}
+
+			av, as, asp := m.CellFactors(cx, cy, true)
+			wv, ws, wsp = g.WeatherFactors(weather[cx][cy])
+			if av != wv || as != ws || asp != wsp {
+				t.Errorf("cell %d, %d: aerial factors %v %v %v, want %v %v %v", cx, cy, av, as, asp, wv, ws, wsp)
+			}
+		}
+	}
+
+	// Points outside of the world fall into the closest cell.
+	tests := []struct {
+		x, y   float64
+		cx, cy int
+	}{
+		{-10, -10, 0, 0},
+		{400, 400, 3, 3},
+		{1000, 150, 3, 1},
+		{250, -1, 2, 0},
+	}
+	for _, tt := range tests {
+		if cx, cy := m.Cell(tt.x, tt.y); cx != tt.cx || cy != tt.cy {
+			t.Errorf("Cell(%v, %v) = %d, %d, want %d, %d", tt.x, tt.y, cx, cy, tt.cx, tt.cy)
+		}
+	}
+
+	v := g.NewVehicle(Vehicle_Fighter)
+	v.X, v.Y = 50, 250
+	if vision, _, _ := m.VehicleFactors(v); vision != g.RainWeatherVisionFactor {
+		t.Errorf("fighter in the rain: vision factor %v, want %v", vision, g.RainWeatherVisionFactor)
+	}
+}
+
+func TestTerrainMapKeepsGrids(t *testing.T) {
+	g := DefaultGame(1)
+	terrain, weather := terrainGrids()
+	m := NewTerrainMap(g)
+	m.Update(&World{Width: 400, Height: 400, TerrainByCellXY: terrain, WeatherByCellXY: weather})
+
+	// Later ticks come without the grids, the map puts them back.
+	w := &World{Width: 400, Height: 400}
+	m.Update(w)
+	if len(w.TerrainByCellXY) != 4 || &w.TerrainByCellXY[0] != &terrain[0] || &w.WeatherByCellXY[0] != &weather[0] {
+		t.Errorf("grids not restored into the world: %v, %v", w.TerrainByCellXY, w.WeatherByCellXY)
+	}
+	if _, _, speed := m.Factors(150, 50, false); speed != g.SwampTerrainSpeedFactor {
+		t.Errorf("swamp speed factor %v after a tick without grids, want %v", speed, g.SwampTerrainSpeedFactor)
+	}
+
+	// New grids replace the cached factors.
+	forest := [][]Terrain{{Terrain_Forest}}
+	m.Update(&World{Width: 400, Height: 400, TerrainByCellXY: forest, WeatherByCellXY: [][]Weather{{Weather_Cloud}}})
+	if cols, rows := m.Size(); cols != 1 || rows != 1 {
+		t.Fatalf("Size() = %d, %d after new grids, want 1, 1", cols, rows)
+	}
+	if _, stealth, _ := m.Factors(350, 350, false); stealth != g.ForestTerrainStealthFactor {
+		t.Errorf("forest stealth factor %v after new grids, want %v", stealth, g.ForestTerrainStealthFactor)
+	}
+}
@@ -95,3 +95,27 @@ type Vehicle struct {
 	 */
 	Groups []int
 }
+
+/**
+ * Применяет к технике изменения, полученные в {@code World.VehicleUpdates}.
+ */
+func (v *Vehicle) Apply(u *VehicleUpdate) {
+	v.X = u.X
+	v.Y = u.Y
+	v.Durability = u.Durability
+	v.RemainingAttackCooldownTicks = u.RemainingAttackCooldownTicks
+	v.Selected = u.Selected
+	v.Groups = append(v.Groups[:0], u.Groups...)
+}
+
+/**
+ * @return Возвращает {@code true}, если техника входит в указанную группу.
+ */
+func (v *Vehicle) InGroup(group int) bool {
+	for _, g := range v.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
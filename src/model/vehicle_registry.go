@@ -0,0 +1,94 @@
+package model
+
+import "sort"
+
+// VehicleRegistry keeps the full state of every known vehicle by folding
+// World.NewVehicles and World.VehicleUpdates together tick after tick.
+type VehicleRegistry struct {
+	vehicles map[int64]*Vehicle
+	me       int64
+}
+
+func NewVehicleRegistry() *VehicleRegistry {
+	return &VehicleRegistry{
+		vehicles: make(map[int64]*Vehicle),
+		me:       -1,
+	}
+}
+
+// Update applies the deltas of the given tick. Vehicles whose durability
+// drops to zero are removed from the registry.
+func (r *VehicleRegistry) Update(w *World) {
+	if me := w.MyPlayer(); me != nil {
+		r.me = me.Id
+	}
+
+	for _, v := range w.NewVehicles {
+		nv := *v
+		nv.Groups = append([]int(nil), v.Groups...)
+		r.vehicles[v.Id] = &nv
+	}
+
+	for _, u := range w.VehicleUpdates {
+		v, ok := r.vehicles[u.Id]
+		if !ok {
+			continue
+		}
+
+		if u.Durability <= 0 {
+			delete(r.vehicles, u.Id)
+			continue
+		}
+
+		v.Apply(u)
+	}
+}
+
+// Get returns the vehicle with the given id or nil.
+func (r *VehicleRegistry) Get(id int64) *Vehicle {
+	return r.vehicles[id]
+}
+
+func (r *VehicleRegistry) Len() int {
+	return len(r.vehicles)
+}
+
+// All returns every known vehicle ordered by id.
+func (r *VehicleRegistry) All() []*Vehicle {
+	return r.Filter(nil)
+}
+
+func (r *VehicleRegistry) Mine() []*Vehicle {
+	return r.Filter(func(v *Vehicle) bool { return v.PlayerId == r.me })
+}
+
+func (r *VehicleRegistry) Enemy() []*Vehicle {
+	return r.Filter(func(v *Vehicle) bool { return v.PlayerId != r.me })
+}
+
+func (r *VehicleRegistry) ByType(t VehicleType) []*Vehicle {
+	return r.Filter(func(v *Vehicle) bool { return v.Type == t })
+}
+
+func (r *VehicleRegistry) MineByType(t VehicleType) []*Vehicle {
+	return r.Filter(func(v *Vehicle) bool { return v.PlayerId == r.me && v.Type == t })
+}
+
+func (r *VehicleRegistry) EnemyByType(t VehicleType) []*Vehicle {
+	return r.Filter(func(v *Vehicle) bool { return v.PlayerId != r.me && v.Type == t })
+}
+
+// Filter returns the vehicles accepted by f ordered by id. A nil f accepts
+// every vehicle.
+func (r *VehicleRegistry) Filter(f func(*Vehicle) bool) []*Vehicle {
+	vehicles := make([]*Vehicle, 0, len(r.vehicles))
+	for _, v := range r.vehicles {
+		if f == nil || f(v) {
+			vehicles = append(vehicles, v)
+		}
+	}
+
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].Id < vehicles[j].Id })
+
+	return vehicles
+}
@@ -0,0 +1,88 @@
+package model
+
+import "testing"
+
+func registryIds(vehicles []*Vehicle) []int64 {
+	ids := make([]int64, len(vehicles))
+	for i, v := range vehicles {
+		ids[i] = v.Id
+	}
+	return ids
+}
+
+func equalIds(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestVehicleRegistry(t *testing.T) {
+	g := DefaultGame(1)
+	players := []*Player{{Id: 1, Me: true}, {Id: 2}}
+
+	var fresh []*Vehicle
+	for i, typ := range []VehicleType{Vehicle_Tank, Vehicle_Fighter, Vehicle_Tank, Vehicle_Arrv} {
+		v := g.NewVehicle(typ)
+		v.Id, v.PlayerId, v.X, v.Y = int64(4-i), int64(1+i%2), float64(10*i), 5
+		v.Groups = []int{1}
+		fresh = append(fresh, v)
+	}
+
+	r := NewVehicleRegistry()
+	r.Update(&World{TickIndex: 0, Players: players, NewVehicles: fresh})
+
+	if got, want := registryIds(r.All()), []int64{1, 2, 3, 4}; !equalIds(got, want) {
+		t.Fatalf("All() = %v, want %v", got, want)
+	}
+	if got, want := registryIds(r.Mine()), []int64{2, 4}; !equalIds(got, want) {
+		t.Errorf("Mine() = %v, want %v", got, want)
+	}
+	if got, want := registryIds(r.Enemy()), []int64{1, 3}; !equalIds(got, want) {
+		t.Errorf("Enemy() = %v, want %v", got, want)
+	}
+	if got, want := registryIds(r.MineByType(Vehicle_Tank)), []int64{2, 4}; !equalIds(got, want) {
+		t.Errorf("MineByType(tank) = %v, want %v", got, want)
+	}
+	if got, want := registryIds(r.EnemyByType(Vehicle_Arrv)), []int64{1}; !equalIds(got, want) {
+		t.Errorf("EnemyByType(arrv) = %v, want %v", got, want)
+	}
+
+	// The registry keeps its own copies.
+	fresh[0].X, fresh[0].Groups[0] = 999, 7
+	if v := r.Get(4); v.X == 999 || !v.InGroup(1) {
+		t.Errorf("registry shares the vehicle of the world: %+v", v)
+	}
+
+	r.Update(&World{TickIndex: 1, Players: players, VehicleUpdates: []*VehicleUpdate{
+		{Id: 4, X: 11, Y: 12, Durability: 60, RemainingAttackCooldownTicks: 3, Selected: true, Groups: []int{2, 3}},
+		{Id: 99, X: 1, Y: 1, Durability: 100},
+	}})
+
+	v := r.Get(4)
+	if v.X != 11 || v.Y != 12 || v.Durability != 60 || v.RemainingAttackCooldownTicks != 3 ||
+		!v.Selected || v.InGroup(1) || !v.InGroup(2) || !v.InGroup(3) {
+		t.Errorf("update not applied: %+v", v)
+	}
+	if v.MaxDurability != g.TankDurability || v.Type != Vehicle_Tank {
+		t.Errorf("update lost the static fields: %+v", v)
+	}
+	if r.Get(99) != nil || r.Len() != 4 {
+		t.Errorf("an update of an unknown vehicle added it: %d vehicles", r.Len())
+	}
+
+	// A vehicle is gone once its durability drops to 0, whether destroyed or
+	// out of sight.
+	r.Update(&World{TickIndex: 2, Players: players, VehicleUpdates: []*VehicleUpdate{{Id: 3, X: 20, Y: 5}}})
+	if r.Get(3) != nil || r.Len() != 3 {
+		t.Errorf("vehicle with durability 0 still registered: %d vehicles", r.Len())
+	}
+	if got, want := registryIds(r.Enemy()), []int64{1}; !equalIds(got, want) {
+		t.Errorf("Enemy() = %v after the death, want %v", got, want)
+	}
+}
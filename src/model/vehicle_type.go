@@ -1,5 +1,7 @@
 package model
 
+import "fmt"
+
 /**
  * Тип техники.
  */
@@ -34,3 +36,18 @@ const (
 	 */
 	Vehicle_Tank
 )
+
+var vehicleTypeNames = []string{"arrv", "fighter", "helicopter", "ifv", "tank"}
+
+/**
+ * Название типа техники.
+ */
+func (t VehicleType) String() string {
+	if t == Vehicle_None {
+		return "none"
+	}
+	if int(t) < len(vehicleTypeNames) {
+		return vehicleTypeNames[t]
+	}
+	return fmt.Sprintf("VehicleType(%d)", byte(t))
+}
@@ -47,6 +47,17 @@ type World struct {
 	 * В зависимости от реализации, объекты, задающие сооружения, могут пересоздаваться после каждого тика.
 	 */
 	Facilities []*Facility
+
+	/**
+	 * Полное состояние всей известной техники, собранное из {@code NewVehicles} и {@code VehicleUpdates}
+	 * всех предыдущих тиков.
+	 */
+	Vehicles *VehicleRegistry
+
+	/**
+	 * Карта местности и погоды, сохраняющая {@code TerrainByCellXY} и {@code WeatherByCellXY} после нулевого тика.
+	 */
+	Map *TerrainMap
 }
 
 /**
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	. "model"
+)
+
+// CheckMoves validates every move of the strategy against the game rules
+// before it is sent. With MoveCheck_Reject a move breaking them is replaced
+// by Action_None, which doesn't cost an action; with MoveCheck_Repair it is
+// fixed if possible, see MoveValidator.Repair. report, if set, receives the
+// violations found.
+func CheckMoves(mode MoveCheck, report func(w *World, err error)) Middleware {
+	return func(next Strategy) Strategy {
+		if mode == MoveCheck_Off {
+			return next
+		}
+		return &checkedStrategy{strategy: next, mode: mode, report: report}
+	}
+}
+
+type checkedStrategy struct {
+	strategy  Strategy
+	mode      MoveCheck
+	report    func(w *World, err error)
+	validator *MoveValidator
+}
+
+func (c *checkedStrategy) Move(p *Player, w *World, g *Game, m *Move) {
+	c.MoveContext(context.Background(), p, w, g, m)
+}
+
+func (c *checkedStrategy) MoveContext(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+	moveContext(ctx, c.strategy, p, w, g, m)
+
+	if c.validator == nil {
+		c.validator = NewMoveValidator(g)
+	}
+
+	var err error
+	if c.mode == MoveCheck_Repair {
+		err = c.validator.Repair(p, w, m)
+	} else if err = c.validator.Validate(p, w, m); err != nil {
+		*m = *NewMove()
+	}
+
+	if err != nil && c.report != nil {
+		c.report(w, err)
+	}
+}
@@ -0,0 +1,157 @@
+// Package pathfinding finds the fastest routes across the terrain and weather
+// grid. Ground vehicles are slowed by swamps and forests, aerial ones by
+// clouds and rain; a route is planned for the slowest vehicle of a group in
+// every cell so that the whole group can follow it together.
+package pathfinding
+
+import (
+	"container/heap"
+	"math"
+	. "model"
+)
+
+// Grid holds for every terrain and weather cell the speed at which a group
+// of vehicles can cross it.
+type Grid struct {
+	cols, rows   int
+	cellW, cellH float64
+
+	// speed per cell, indexed by y*cols + x, and its maximum.
+	speed    []float64
+	maxSpeed float64
+}
+
+// NewGrid builds the grid for a group made of the given vehicle types. The
+// speed of a cell is the speed of the slowest type in it.
+func NewGrid(g *Game, m *TerrainMap, types ...VehicleType) *Grid {
+	cols, rows := m.Size()
+	if cols <= 0 || rows <= 0 {
+		cols, rows = 1, 1
+	}
+	cellW, cellH := m.CellSize()
+
+	grid := &Grid{
+		cols:  cols,
+		rows:  rows,
+		cellW: cellW,
+		cellH: cellH,
+		speed: make([]float64, cols*rows),
+	}
+
+	vehicles := make([]*Vehicle, 0, len(types))
+	for _, t := range types {
+		vehicles = append(vehicles, g.NewVehicle(t))
+	}
+
+	for cy := 0; cy < rows; cy++ {
+		for cx := 0; cx < cols; cx++ {
+			speed := math.Inf(1)
+			for _, v := range vehicles {
+				_, _, factor := m.CellFactors(cx, cy, v.Aerial)
+				speed = math.Min(speed, v.MaxSpeed*factor)
+			}
+			if math.IsInf(speed, 1) {
+				speed = 0
+			}
+			grid.speed[cy*cols+cx] = speed
+			grid.maxSpeed = math.Max(grid.maxSpeed, speed)
+		}
+	}
+
+	return grid
+}
+
+// Cell returns the cell containing the point, clamped to the grid.
+func (g *Grid) Cell(x, y float64) (cx, cy int) {
+	cx = int(x / g.cellW)
+	cy = int(y / g.cellH)
+	return clamp(cx, g.cols), clamp(cy, g.rows)
+}
+
+func clamp(c, n int) int {
+	if c < 0 {
+		return 0
+	}
+	if c >= n {
+		return n - 1
+	}
+	return c
+}
+
+// Center returns the centre of the cell.
+func (g *Grid) Center(cx, cy int) (x, y float64) {
+	return (float64(cx) + 0.5) * g.cellW, (float64(cy) + 0.5) * g.cellH
+}
+
+// Speed returns the speed of the group at the point.
+func (g *Grid) Speed(x, y float64) float64 {
+	cx, cy := g.Cell(x, y)
+	return g.speed[cy*g.cols+cx]
+}
+
+// neighbours lists the 8 directions a route can take from a cell.
+var neighbours = [8][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+// stepTicks returns the ticks needed to go from the centre of cell a to the
+// centre of the neighbouring cell b: half of the way at the speed of each.
+func (g *Grid) stepTicks(a, b int) float64 {
+	ax, ay := a%g.cols, a/g.cols
+	bx, by := b%g.cols, b/g.cols
+	d := math.Hypot(float64(bx-ax)*g.cellW, float64(by-ay)*g.cellH)
+
+	sa, sb := g.speed[a], g.speed[b]
+	if sa <= 0 || sb <= 0 {
+		return math.Inf(1)
+	}
+	return d/2/sa + d/2/sb
+}
+
+// queue is a priority queue of cells for Dijkstra and A*.
+type queue struct {
+	cells    []int
+	priority []float64
+	index    map[int]int
+}
+
+func newQueue() *queue {
+	return &queue{index: make(map[int]int)}
+}
+
+func (q *queue) Len() int           { return len(q.cells) }
+func (q *queue) Less(i, j int) bool { return q.priority[i] < q.priority[j] }
+
+func (q *queue) Swap(i, j int) {
+	q.cells[i], q.cells[j] = q.cells[j], q.cells[i]
+	q.priority[i], q.priority[j] = q.priority[j], q.priority[i]
+	q.index[q.cells[i]] = i
+	q.index[q.cells[j]] = j
+}
+
+func (q *queue) Push(x interface{}) {
+	q.index[x.(int)] = len(q.cells)
+	q.cells = append(q.cells, x.(int))
+	q.priority = append(q.priority, 0)
+}
+
+func (q *queue) Pop() interface{} {
+	n := len(q.cells) - 1
+	c := q.cells[n]
+	q.cells, q.priority = q.cells[:n], q.priority[:n]
+	delete(q.index, c)
+	return c
+}
+
+// update sets the priority of the cell, adding it if needed.
+func (q *queue) update(cell int, priority float64) {
+	i, ok := q.index[cell]
+	if !ok {
+		heap.Push(q, cell)
+		i = q.index[cell]
+	}
+	q.priority[i] = priority
+	heap.Fix(q, i)
+}
+
+func (q *queue) pop() int {
+	return heap.Pop(q).(int)
+}
@@ -0,0 +1,42 @@
+package pathfinding
+
+import (
+	"math"
+	. "model"
+	"testing"
+)
+
+// testGrid returns a grid of 10 by 10 cells with the given speeds, row by
+// row.
+func testGrid(cols, rows int, speed []float64) *Grid {
+	g := &Grid{cols: cols, rows: rows, cellW: 10, cellH: 10, speed: speed}
+	for _, s := range speed {
+		g.maxSpeed = math.Max(g.maxSpeed, s)
+	}
+	return g
+}
+
+func TestNewGridSlowestType(t *testing.T) {
+	g := DefaultGame(1)
+	m := NewTerrainMap(g)
+	m.Update(&World{
+		Width: 200, Height: 200,
+		TerrainByCellXY: [][]Terrain{{Terrain_Plain, Terrain_Swamp}, {Terrain_Plain, Terrain_Plain}},
+		WeatherByCellXY: [][]Weather{{Weather_Clear, Weather_Clear}, {Weather_Rain, Weather_Clear}},
+	})
+
+	grid := NewGrid(g, m, Vehicle_Tank, Vehicle_Fighter)
+	tests := []struct {
+		x, y float64
+		want float64
+	}{
+		{50, 50, g.TankSpeed},
+		{50, 150, g.TankSpeed * g.SwampTerrainSpeedFactor},
+		{150, 50, math.Min(g.TankSpeed, g.FighterSpeed*g.RainWeatherSpeedFactor)},
+	}
+	for _, tt := range tests {
+		if got := grid.Speed(tt.x, tt.y); got != tt.want {
+			t.Errorf("Speed(%v, %v) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
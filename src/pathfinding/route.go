@@ -0,0 +1,337 @@
+package pathfinding
+
+import (
+	"context"
+	"math"
+	. "model"
+)
+
+// Waypoint is a point of a route together with the speed at which the group
+// travels to it from the previous waypoint.
+type Waypoint struct {
+	X, Y  float64
+	Speed float64
+}
+
+// Route is the fastest way from one point to another.
+type Route struct {
+	FromX, FromY float64
+	Waypoints    []Waypoint
+
+	// Ticks is the estimated travel time.
+	Ticks float64
+
+	// Partial is set if the search was stopped before reaching the
+	// destination; the route then ends at the explored cell closest to it.
+	Partial bool
+}
+
+// Path returns the fastest route between the points found with A*, or nil if
+// the destination can't be reached.
+func (g *Grid) Path(fromX, fromY, toX, toY float64) *Route {
+	return g.PathContext(context.Background(), fromX, fromY, toX, toY)
+}
+
+// PathContext is Path stopping the search when ctx is done, with a partial
+// route towards the destination.
+func (g *Grid) PathContext(ctx context.Context, fromX, fromY, toX, toY float64) *Route {
+	sx, sy := g.Cell(fromX, fromY)
+	tx, ty := g.Cell(toX, toY)
+
+	cells, partial := g.search(ctx, sy*g.cols+sx, ty*g.cols+tx)
+	if cells == nil {
+		return nil
+	}
+	if partial {
+		last := cells[len(cells)-1]
+		toX, toY = g.Center(last%g.cols, last/g.cols)
+		r := g.route(fromX, fromY, toX, toY, cells)
+		r.Partial = true
+		return r
+	}
+
+	return g.route(fromX, fromY, toX, toY, cells)
+}
+
+// search returns the cells of the fastest path from start to goal found with
+// A*, or nil if goal can't be reached. If ctx is done first the path ends at
+// the explored cell closest to goal and partial is set.
+func (g *Grid) search(ctx context.Context, start, goal int) (cells []int, partial bool) {
+	cost := make([]float64, len(g.speed))
+	prev := make([]int, len(g.speed))
+	for i := range cost {
+		cost[i] = math.Inf(1)
+		prev[i] = -1
+	}
+	cost[start] = 0
+
+	gx, gy := g.Center(goal%g.cols, goal/g.cols)
+	estimate := func(cell int) float64 {
+		if g.maxSpeed <= 0 {
+			return 0
+		}
+		cx, cy := g.Center(cell%g.cols, cell/g.cols)
+		return math.Hypot(gx-cx, gy-cy) / g.maxSpeed
+	}
+
+	q := newQueue()
+	q.update(start, estimate(start))
+	closed := make([]bool, len(g.speed))
+	nearest := start
+
+	for n := 0; q.Len() > 0; n++ {
+		if n%256 == 0 && ctx.Err() != nil {
+			return chain(prev, nearest), true
+		}
+		cell := q.pop()
+		if cell == goal {
+			break
+		}
+		closed[cell] = true
+		if estimate(cell) < estimate(nearest) {
+			nearest = cell
+		}
+
+		g.eachNeighbour(cell, func(n int) {
+			if closed[n] {
+				return
+			}
+			if c := cost[cell] + g.stepTicks(cell, n); c < cost[n] {
+				cost[n], prev[n] = c, cell
+				q.update(n, c+estimate(n))
+			}
+		})
+	}
+
+	if math.IsInf(cost[goal], 1) {
+		return nil, false
+	}
+	return chain(prev, goal), false
+}
+
+func (g *Grid) eachNeighbour(cell int, f func(int)) {
+	cx, cy := cell%g.cols, cell/g.cols
+	for _, d := range neighbours {
+		nx, ny := cx+d[0], cy+d[1]
+		if nx >= 0 && nx < g.cols && ny >= 0 && ny < g.rows {
+			f(ny*g.cols + nx)
+		}
+	}
+}
+
+// route turns a chain of cells into waypoints. Consecutive steps in the same
+// direction at the same speed are merged into one waypoint; the first and
+// the last cell are replaced by the exact end points.
+func (g *Grid) route(fromX, fromY, toX, toY float64, cells []int) *Route {
+	r := &Route{FromX: fromX, FromY: fromY}
+
+	x, y := fromX, fromY
+	add := func(nx, ny float64, speed float64) {
+		if n := len(r.Waypoints); n > 0 && speed == r.Waypoints[n-1].Speed {
+			last := r.Waypoints[n-1]
+			px, py := fromX, fromY
+			if n > 1 {
+				px, py = r.Waypoints[n-2].X, r.Waypoints[n-2].Y
+			}
+			if collinear(px, py, last.X, last.Y, nx, ny) {
+				r.Waypoints[n-1].X, r.Waypoints[n-1].Y = nx, ny
+				r.Ticks += math.Hypot(nx-x, ny-y) / speed
+				x, y = nx, ny
+				return
+			}
+		}
+		r.Waypoints = append(r.Waypoints, Waypoint{X: nx, Y: ny, Speed: speed})
+		r.Ticks += math.Hypot(nx-x, ny-y) / speed
+		x, y = nx, ny
+	}
+
+	for i := 1; i < len(cells)-1; i++ {
+		cx, cy := g.Center(cells[i]%g.cols, cells[i]/g.cols)
+		add(cx, cy, g.segmentSpeed(x, y, cx, cy))
+	}
+	if x != toX || y != toY {
+		add(toX, toY, g.segmentSpeed(x, y, toX, toY))
+	}
+
+	return r
+}
+
+func collinear(ax, ay, bx, by, cx, cy float64) bool {
+	cross := (bx-ax)*(cy-ay) - (by-ay)*(cx-ax)
+	return math.Abs(cross) < 1e-6*math.Max(1, math.Hypot(bx-ax, by-ay)*math.Hypot(cx-ax, cy-ay))
+}
+
+// segmentSpeed returns the lowest speed along the straight segment, sampled
+// every quarter of a cell.
+func (g *Grid) segmentSpeed(x0, y0, x1, y1 float64) float64 {
+	d := math.Hypot(x1-x0, y1-y0)
+	steps := int(math.Ceil(d/(math.Min(g.cellW, g.cellH)/4))) + 1
+
+	speed := math.Inf(1)
+	for i := 0; i <= steps; i++ {
+		f := float64(i) / float64(steps)
+		speed = math.Min(speed, g.Speed(x0+(x1-x0)*f, y0+(y1-y0)*f))
+	}
+	return speed
+}
+
+// Moves returns one Action_Move per waypoint, to be sent to the selected
+// group one after another as it reaches each waypoint. Action_Move takes the
+// displacement from the current position, and MaxSpeed keeps the faster
+// vehicles with the slowest one.
+func (r *Route) Moves() []*Move {
+	moves := make([]*Move, 0, len(r.Waypoints))
+	x, y := r.FromX, r.FromY
+	for _, w := range r.Waypoints {
+		moves = append(moves, MoveBy(w.X-x, w.Y-y, w.Speed))
+		x, y = w.X, w.Y
+	}
+	return moves
+}
+
+// FlowField tells from every cell of the grid the fastest way towards a
+// single target.
+type FlowField struct {
+	grid   *Grid
+	toX    float64
+	toY    float64
+	target int
+	ticks  []float64
+	next   []int
+}
+
+// FlowField computes with Dijkstra the time to reach the target from every
+// cell and the cell to go to next.
+func (g *Grid) FlowField(toX, toY float64) *FlowField {
+	tx, ty := g.Cell(toX, toY)
+	f := &FlowField{
+		grid:   g,
+		toX:    toX,
+		toY:    toY,
+		target: ty*g.cols + tx,
+		ticks:  make([]float64, len(g.speed)),
+		next:   make([]int, len(g.speed)),
+	}
+	for i := range f.ticks {
+		f.ticks[i] = math.Inf(1)
+		f.next[i] = -1
+	}
+	f.ticks[f.target] = 0
+
+	q := newQueue()
+	q.update(f.target, 0)
+	done := make([]bool, len(g.speed))
+
+	for q.Len() > 0 {
+		cell := q.pop()
+		done[cell] = true
+
+		g.eachNeighbour(cell, func(n int) {
+			if done[n] {
+				return
+			}
+			if c := f.ticks[cell] + g.stepTicks(n, cell); c < f.ticks[n] {
+				f.ticks[n], f.next[n] = c, cell
+				q.update(n, c)
+			}
+		})
+	}
+
+	return f
+}
+
+// Ticks returns the estimated time to reach the target from the point, +Inf
+// if it can't be reached.
+func (f *FlowField) Ticks(x, y float64) float64 {
+	cx, cy := f.grid.Cell(x, y)
+	return f.ticks[cy*f.grid.cols+cx]
+}
+
+// Next returns the point to head for from (x, y): the centre of the next
+// cell, or the target itself from its own cell. ok is false if the target
+// can't be reached.
+func (f *FlowField) Next(x, y float64) (nx, ny float64, ok bool) {
+	cx, cy := f.grid.Cell(x, y)
+	cell := cy*f.grid.cols + cx
+	if cell == f.target {
+		return f.toX, f.toY, true
+	}
+	next := f.next[cell]
+	if next < 0 {
+		return x, y, false
+	}
+	nx, ny = f.grid.Center(next%f.grid.cols, next/f.grid.cols)
+	return nx, ny, true
+}
+
+// Route follows the field from (x, y) to the target.
+func (f *FlowField) Route(x, y float64) *Route {
+	cx, cy := f.grid.Cell(x, y)
+	cell := cy*f.grid.cols + cx
+	if math.IsInf(f.ticks[cell], 1) {
+		return nil
+	}
+
+	cells := []int{cell}
+	for cell != f.target {
+		cell = f.next[cell]
+		cells = append(cells, cell)
+	}
+	return f.grid.route(x, y, f.toX, f.toY, cells)
+}
+
+// Cheapest returns the chain of cells from start to goal of the smallest total
+// step cost on a cols×rows grid with 8 neighbours per cell, found with
+// Dijkstra, or nil if goal can't be reached. Cells are indexed by y*cols + x
+// and step returns the cost of moving between neighbours, +Inf to forbid it.
+func Cheapest(cols, rows, start, goal int, step func(from, to int) float64) []int {
+	g := &Grid{cols: cols, rows: rows}
+
+	cost := make([]float64, cols*rows)
+	prev := make([]int, cols*rows)
+	for i := range cost {
+		cost[i] = math.Inf(1)
+		prev[i] = -1
+	}
+	cost[start] = 0
+
+	q := newQueue()
+	q.update(start, 0)
+	done := make([]bool, cols*rows)
+
+	for q.Len() > 0 {
+		cell := q.pop()
+		if cell == goal {
+			break
+		}
+		done[cell] = true
+
+		g.eachNeighbour(cell, func(n int) {
+			if done[n] {
+				return
+			}
+			if c := cost[cell] + step(cell, n); c < cost[n] {
+				cost[n], prev[n] = c, cell
+				q.update(n, c)
+			}
+		})
+	}
+
+	if math.IsInf(cost[goal], 1) {
+		return nil
+	}
+
+	return chain(prev, goal)
+}
+
+// chain follows prev back from goal and returns the cells in travel order.
+func chain(prev []int, goal int) []int {
+	var cells []int
+	for c := goal; c != -1; c = prev[c] {
+		cells = append(cells, c)
+	}
+	for i, j := 0, len(cells)-1; i < j; i, j = i+1, j-1 {
+		cells[i], cells[j] = cells[j], cells[i]
+	}
+	return cells
+}
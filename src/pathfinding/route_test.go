@@ -0,0 +1,166 @@
+package pathfinding
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomGrid returns a grid where about a fifth of the cells can't be
+// crossed and the others have one of a few speeds.
+func randomGrid(rnd *rand.Rand, cols, rows int) *Grid {
+	speeds := []float64{0, 0.3, 0.6, 1, 1}
+	speed := make([]float64, cols*rows)
+	for i := range speed {
+		speed[i] = speeds[rnd.Intn(len(speeds))]
+	}
+	return testGrid(cols, rows, speed)
+}
+
+// pathTicks returns the cost of the chain of cells, -1 if two of them aren't
+// neighbours.
+func pathTicks(g *Grid, cells []int) float64 {
+	ticks := 0.0
+	for i := 1; i < len(cells); i++ {
+		a, b := cells[i-1], cells[i]
+		if dx, dy := a%g.cols-b%g.cols, a/g.cols-b/g.cols; dx*dx > 1 || dy*dy > 1 || a == b {
+			return -1
+		}
+		ticks += g.stepTicks(a, b)
+	}
+	return ticks
+}
+
+func TestPathOptimal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for round := 0; round < 200; round++ {
+		g := randomGrid(rnd, 12, 12)
+		start, goal := rnd.Intn(144), rnd.Intn(144)
+
+		// Dijkstra explores every cheaper cell, A* must agree on the cost.
+		want := Cheapest(g.cols, g.rows, start, goal, g.stepTicks)
+		got, partial := g.search(context.Background(), start, goal)
+		if partial || (got == nil) != (want == nil) {
+			t.Fatalf("round %d: A* found %v, partial %v, Dijkstra %v", round, got, partial, want)
+		}
+		if got == nil {
+			continue
+		}
+		if got[0] != start || got[len(got)-1] != goal {
+			t.Errorf("round %d: path %v doesn't lead from %d to %d", round, got, start, goal)
+		}
+		if a, d := pathTicks(g, got), pathTicks(g, want); a < 0 || math.Abs(a-d) > 1e-9 {
+			t.Errorf("round %d: A* path takes %v ticks, Dijkstra %v", round, a, d)
+		}
+	}
+}
+
+func TestPathAroundWall(t *testing.T) {
+	// A wall across the middle column with a gap in the bottom row.
+	speed := make([]float64, 5*5)
+	for i := range speed {
+		speed[i] = 1
+		if i%5 == 2 && i/5 < 4 {
+			speed[i] = 0
+		}
+	}
+	g := testGrid(5, 5, speed)
+
+	r := g.Path(5, 5, 45, 5)
+	if r == nil || r.Partial {
+		t.Fatalf("Path = %+v, want a full route", r)
+	}
+	x, y := r.FromX, r.FromY
+	for _, w := range r.Waypoints {
+		if w.Speed != 1 || g.segmentSpeed(x, y, w.X, w.Y) <= 0 {
+			t.Errorf("waypoint %+v from %v, %v crosses the wall", w, x, y)
+		}
+		x, y = w.X, w.Y
+	}
+	if x != 45 || y != 5 {
+		t.Errorf("route ends at %v, %v, want 45, 5", x, y)
+	}
+
+	// The moves add up to the whole way.
+	dx, dy := 0.0, 0.0
+	for _, m := range r.Moves() {
+		dx, dy = dx+m.X, dy+m.Y
+	}
+	if math.Abs(dx-40) > 1e-9 || math.Abs(dy) > 1e-9 {
+		t.Errorf("moves add up to %v, %v, want 40, 0", dx, dy)
+	}
+
+	// Without the gap there is no way.
+	speed[4*5+2] = 0
+	if r := g.Path(5, 5, 45, 5); r != nil {
+		t.Errorf("Path through a closed wall = %+v", r)
+	}
+}
+
+func TestPathPartial(t *testing.T) {
+	g := testGrid(20, 1, make([]float64, 20))
+	for i := range g.speed {
+		g.speed[i] = 1
+	}
+	g.maxSpeed = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := g.PathContext(ctx, 5, 5, 195, 5)
+	if r == nil || !r.Partial {
+		t.Errorf("PathContext with a cancelled context = %+v, want a partial route", r)
+	}
+}
+
+func TestFlowField(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+
+	for round := 0; round < 50; round++ {
+		g := randomGrid(rnd, 10, 10)
+		target := rnd.Intn(100)
+		tx, ty := g.Center(target%g.cols, target/g.cols)
+		f := g.FlowField(tx+1, ty+1)
+
+		for cell := 0; cell < 100; cell++ {
+			x, y := g.Center(cell%g.cols, cell/g.cols)
+			ticks := f.Ticks(x, y)
+
+			want := Cheapest(g.cols, g.rows, cell, target, g.stepTicks)
+			if want == nil {
+				if !math.IsInf(ticks, 1) || f.Route(x, y) != nil {
+					t.Errorf("round %d, cell %d: unreachable target takes %v ticks", round, cell, ticks)
+				}
+				if _, _, ok := f.Next(x, y); ok {
+					t.Errorf("round %d, cell %d: Next leads to an unreachable target", round, cell)
+				}
+				continue
+			}
+			if math.Abs(ticks-pathTicks(g, want)) > 1e-9 {
+				t.Errorf("round %d, cell %d: %v ticks, Dijkstra %v", round, cell, ticks, pathTicks(g, want))
+			}
+
+			// Following the field gets closer to the target on every step.
+			nx, ny, ok := f.Next(x, y)
+			if !ok {
+				t.Errorf("round %d, cell %d: no next step", round, cell)
+				continue
+			}
+			if cell == target {
+				if nx != tx+1 || ny != ty+1 {
+					t.Errorf("round %d: Next from the target cell = %v, %v, want the target", round, nx, ny)
+				}
+				continue
+			}
+			if next := f.Ticks(nx, ny); next >= ticks {
+				t.Errorf("round %d, cell %d: next cell takes %v ticks, not less than %v", round, cell, next, ticks)
+			}
+
+			r := f.Route(x, y)
+			if last := r.Waypoints[len(r.Waypoints)-1]; last.X != tx+1 || last.Y != ty+1 {
+				t.Errorf("round %d, cell %d: route ends at %v, %v", round, cell, last.X, last.Y)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	. "model"
+	"testing"
+)
+
+func TestNewMoveDefaults(t *testing.T) {
+	want := Move{Action: Action_None, Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}
+	if got := *NewMove(); got != want {
+		t.Errorf("NewMove() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMoveBuildersRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		move *Move
+		want Move
+	}{
+		{"NewMove", NewMove(), Move{Action: Action_None, Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"SelectRect", SelectRect(1, 2, 3, 4), Move{Action: Action_ClearAndSelect, Left: 1, Top: 2, Right: 3, Bottom: 4,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"SelectRect.OfType", SelectRect(0, 0, 1024, 1024).OfType(Vehicle_Tank), Move{Action: Action_ClearAndSelect,
+			Right: 1024, Bottom: 1024, Type: Vehicle_Tank, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"SelectGroup", SelectGroup(3), Move{Action: Action_ClearAndSelect, Group: 3,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"AddRect", AddRect(5, 6, 7, 8), Move{Action: Action_AddToSelection, Left: 5, Top: 6, Right: 7, Bottom: 8,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"AddGroup", AddGroup(2), Move{Action: Action_AddToSelection, Group: 2,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"DeselectRect", DeselectRect(9, 10, 11, 12), Move{Action: Action_Deselect, Left: 9, Top: 10, Right: 11, Bottom: 12,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"DeselectGroup", DeselectGroup(4), Move{Action: Action_Deselect, Group: 4,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"Assign", Assign(5), Move{Action: Action_Assign, Group: 5,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"Dismiss", Dismiss(6), Move{Action: Action_Dismiss, Group: 6,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"Disband", Disband(7), Move{Action: Action_Disband, Group: 7,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"MoveBy", MoveBy(-50, 25.5, 0.3), Move{Action: Action_Move, X: -50, Y: 25.5, MaxSpeed: 0.3,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"RotateAround", RotateAround(100, 200, math.Pi/2).WithMaxSpeed(0.5).WithMaxAngularSpeed(0.01),
+			Move{Action: Action_Rotate, X: 100, Y: 200, Angle: math.Pi / 2, MaxSpeed: 0.5, MaxAngularSpeed: 0.01,
+				Type: Vehicle_None, Factor: 1, FacilityId: -1, VehicleId: -1}},
+		{"ScaleAround", ScaleAround(300, 400, 0.1).WithMaxSpeed(0.2), Move{Action: Action_Scale, X: 300, Y: 400,
+			Factor: 0.1, MaxSpeed: 0.2, Type: Vehicle_None, FacilityId: -1, VehicleId: -1}},
+		{"SetupProduction", SetupProduction(42, Vehicle_Helicopter), Move{Action: Action_SetupVehicleProduction,
+			FacilityId: 42, Type: Vehicle_Helicopter, Factor: 1, VehicleId: -1}},
+		{"SetupProduction none", SetupProduction(42, Vehicle_None), Move{Action: Action_SetupVehicleProduction,
+			FacilityId: 42, Type: Vehicle_None, Factor: 1, VehicleId: -1}},
+		{"Nuke", Nuke(17, 512, 256), Move{Action: Action_TacticalNuclearStrike, VehicleId: 17, X: 512, Y: 256,
+			Type: Vehicle_None, Factor: 1, FacilityId: -1}},
+	}
+
+	for _, tt := range tests {
+		if *tt.move != tt.want {
+			t.Errorf("%s: built %+v, want %+v", tt.name, *tt.move, tt.want)
+			continue
+		}
+
+		var buf bytes.Buffer
+		bw := bufio.NewWriter(&buf)
+		if err := NewWriter(bw).WriteMove(tt.move); err != nil {
+			t.Errorf("%s: WriteMove: %v", tt.name, err)
+			continue
+		}
+		bw.Flush()
+
+		got, err := NewReader(bufio.NewReader(&buf)).ReadMove()
+		if err != nil {
+			t.Errorf("%s: ReadMove: %v", tt.name, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("%s: round trip gave %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package protocol implements both sides of the CodeWars binary protocol.
+// The client and the fake server share these encoders and decoders, so the
+// two sides are always checked against each other.
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ByteOrder = binary.LittleEndian
+
+type MessageType byte
+
+const (
+	Message_GameOver MessageType = iota + 1
+	Message_AuthenticationToken
+	Message_TeamSize
+	Message_ProtocolVersion
+	Message_GameContext
+	Message_PlayerContext
+	Message_Move
+)
+
+var messageNames = map[MessageType]string{
+	Message_GameOver:            "GameOver",
+	Message_AuthenticationToken: "AuthenticationToken",
+	Message_TeamSize:            "TeamSize",
+	Message_ProtocolVersion:     "ProtocolVersion",
+	Message_GameContext:         "GameContext",
+	Message_PlayerContext:       "PlayerContext",
+	Message_Move:                "Move",
+}
+
+func (m MessageType) String() string {
+	if name, ok := messageNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("MessageType(%d)", byte(m))
+}
+
+const Version int = 3
+
+var (
+	ErrGameOver  = errors.New("game over")
+	ErrWrongType = errors.New("wrong message type")
+)
+
+// ProtocolError describes a failure to decode or encode a message. Message is
+// the message being processed and Field is the field that caused the failure.
+type ProtocolError struct {
+	Message MessageType
+	Field   string
+	Err     error
+}
+
+func (e *ProtocolError) Error() string {
+	s := "protocol: "
+	if e.Message != 0 {
+		s += e.Message.String() + " "
+	}
+	if e.Field != "" {
+		s += e.Field + ": "
+	}
+	return s + e.Err.Error()
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// IsEndOfStream reports whether err means the stream ended cleanly between
+// two messages.
+func IsEndOfStream(err error) bool {
+	var perr *ProtocolError
+	return errors.As(err, &perr) && perr.Field == "opcode" && perr.Err == io.EOF
+}
+
+type ByteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+type MessageWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+	Flush() error
+}
@@ -0,0 +1,532 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	. "model"
+)
+
+// Reader decodes protocol messages. The first I/O error is kept and turns all
+// further reads into no-ops, so the message level methods check it once when
+// they are done.
+type Reader struct {
+	r ByteReader
+
+	players    map[int64]*Player
+	facilities map[int64]*Facility
+
+	// game is the last game read, needed to build World.Map.
+	game *Game
+
+	message MessageType
+	err     error
+}
+
+func NewReader(r ByteReader) *Reader {
+	return &Reader{
+		r:          r,
+		players:    make(map[int64]*Player),
+		facilities: make(map[int64]*Facility),
+	}
+}
+
+// Err returns the first error met by the reader.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// ReadTeamSize, ReadGame, ReadContext and ReadMove decode the messages sent to
+// the client, ReadToken, ReadProtocolVersion and ReadMove the ones sent to
+// the server.
+func (r *Reader) ReadGame() (*Game, error) {
+	if err := r.Expect(Message_GameContext); err != nil {
+		return nil, err
+	}
+
+	var g *Game
+
+	if r.readBool("Game") {
+		g = &Game{
+			RandomSeed:                             r.readInt64("RandomSeed"),
+			TickCount:                              r.readInt("TickCount"),
+			WorldWidth:                             r.readFloat64("WorldWidth"),
+			WorldHeight:                            r.readFloat64("WorldHeight"),
+			FogOfWarEnabled:                        r.readBool("FogOfWarEnabled"),
+			VictoryScore:                           r.readInt("VictoryScore"),
+			FacilityCaptureScore:                   r.readInt("FacilityCaptureScore"),
+			VehicleEliminationScore:                r.readInt("VehicleEliminationScore"),
+			ActionDetectionInterval:                r.readInt("ActionDetectionInterval"),
+			BaseActionCount:                        r.readInt("BaseActionCount"),
+			AdditionalActionCountPerControlCenter:  r.readInt("AdditionalActionCountPerControlCenter"),
+			MaxUnitGroup:                           r.readInt("MaxUnitGroup"),
+			TerrainWeatherMapColumnCount:           r.readInt("TerrainWeatherMapColumnCount"),
+			TerrainWeatherMapRowCount:              r.readInt("TerrainWeatherMapRowCount"),
+			PlainTerrainVisionFactor:               r.readFloat64("PlainTerrainVisionFactor"),
+			PlainTerrainStealthFactor:              r.readFloat64("PlainTerrainStealthFactor"),
+			PlainTerrainSpeedFactor:                r.readFloat64("PlainTerrainSpeedFactor"),
+			SwampTerrainVisionFactor:               r.readFloat64("SwampTerrainVisionFactor"),
+			SwampTerrainStealthFactor:              r.readFloat64("SwampTerrainStealthFactor"),
+			SwampTerrainSpeedFactor:                r.readFloat64("SwampTerrainSpeedFactor"),
+			ForestTerrainVisionFactor:              r.readFloat64("ForestTerrainVisionFactor"),
+			ForestTerrainStealthFactor:             r.readFloat64("ForestTerrainStealthFactor"),
+			ForestTerrainSpeedFactor:               r.readFloat64("ForestTerrainSpeedFactor"),
+			ClearWeatherVisionFactor:               r.readFloat64("ClearWeatherVisionFactor"),
+			ClearWeatherStealthFactor:              r.readFloat64("ClearWeatherStealthFactor"),
+			ClearWeatherSpeedFactor:                r.readFloat64("ClearWeatherSpeedFactor"),
+			CloudWeatherVisionFactor:               r.readFloat64("CloudWeatherVisionFactor"),
+			CloudWeatherStealthFactor:              r.readFloat64("CloudWeatherStealthFactor"),
+			CloudWeatherSpeedFactor:                r.readFloat64("CloudWeatherSpeedFactor"),
+			RainWeatherVisionFactor:                r.readFloat64("RainWeatherVisionFactor"),
+			RainWeatherStealthFactor:               r.readFloat64("RainWeatherStealthFactor"),
+			RainWeatherSpeedFactor:                 r.readFloat64("RainWeatherSpeedFactor"),
+			VehicleRadius:                          r.readFloat64("VehicleRadius"),
+			TankDurability:                         r.readInt("TankDurability"),
+			TankSpeed:                              r.readFloat64("TankSpeed"),
+			TankVisionRange:                        r.readFloat64("TankVisionRange"),
+			TankGroundAttackRange:                  r.readFloat64("TankGroundAttackRange"),
+			TankAerialAttackRange:                  r.readFloat64("TankAerialAttackRange"),
+			TankGroundDamage:                       r.readInt("TankGroundDamage"),
+			TankAerialDamage:                       r.readInt("TankAerialDamage"),
+			TankGroundDefence:                      r.readInt("TankGroundDefence"),
+			TankAerialDefence:                      r.readInt("TankAerialDefence"),
+			TankAttackCooldownTicks:                r.readInt("TankAttackCooldownTicks"),
+			TankProductionCost:                     r.readInt("TankProductionCost"),
+			IFVDurability:                          r.readInt("IFVDurability"),
+			IFVSpeed:                               r.readFloat64("IFVSpeed"),
+			IFVVisionRange:                         r.readFloat64("IFVVisionRange"),
+			IFVGroundAttackRange:                   r.readFloat64("IFVGroundAttackRange"),
+			IFVAerialAttackRange:                   r.readFloat64("IFVAerialAttackRange"),
+			IFVGroundDamage:                        r.readInt("IFVGroundDamage"),
+			IFVAerialDamage:                        r.readInt("IFVAerialDamage"),
+			IFVGroundDefence:                       r.readInt("IFVGroundDefence"),
+			IFVAerialDefence:                       r.readInt("IFVAerialDefence"),
+			IFVAttackCooldownTicks:                 r.readInt("IFVAttackCooldownTicks"),
+			IFVProductionCost:                      r.readInt("IFVProductionCost"),
+			ARRVDurability:                         r.readInt("ARRVDurability"),
+			ARRVSpeed:                              r.readFloat64("ARRVSpeed"),
+			ARRVVisionRange:                        r.readFloat64("ARRVVisionRange"),
+			ARRVGroundDefence:                      r.readInt("ARRVGroundDefence"),
+			ARRVAerialDefence:                      r.readInt("ARRVAerialDefence"),
+			ARRVProductionCost:                     r.readInt("ARRVProductionCost"),
+			ARRVRepairRange:                        r.readFloat64("ARRVRepairRange"),
+			ARRVRepairSpeed:                        r.readFloat64("ARRVRepairSpeed"),
+			HelicopterDurability:                   r.readInt("HelicopterDurability"),
+			HelicopterSpeed:                        r.readFloat64("HelicopterSpeed"),
+			HelicopterVisionRange:                  r.readFloat64("HelicopterVisionRange"),
+			HelicopterGroundAttackRange:            r.readFloat64("HelicopterGroundAttackRange"),
+			HelicopterAerialAttackRange:            r.readFloat64("HelicopterAerialAttackRange"),
+			HelicopterGroundDamage:                 r.readInt("HelicopterGroundDamage"),
+			HelicopterAerialDamage:                 r.readInt("HelicopterAerialDamage"),
+			HelicopterGroundDefence:                r.readInt("HelicopterGroundDefence"),
+			HelicopterAerialDefence:                r.readInt("HelicopterAerialDefence"),
+			HelicopterAttackCooldownTicks:          r.readInt("HelicopterAttackCooldownTicks"),
+			HelicopterProductionCost:               r.readInt("HelicopterProductionCost"),
+			FighterDurability:                      r.readInt("FighterDurability"),
+			FighterSpeed:                           r.readFloat64("FighterSpeed"),
+			FighterVisionRange:                     r.readFloat64("FighterVisionRange"),
+			FighterGroundAttackRange:               r.readFloat64("FighterGroundAttackRange"),
+			FighterAerialAttackRange:               r.readFloat64("FighterAerialAttackRange"),
+			FighterGroundDamage:                    r.readInt("FighterGroundDamage"),
+			FighterAerialDamage:                    r.readInt("FighterAerialDamage"),
+			FighterGroundDefence:                   r.readInt("FighterGroundDefence"),
+			FighterAerialDefence:                   r.readInt("FighterAerialDefence"),
+			FighterAttackCooldownTicks:             r.readInt("FighterAttackCooldownTicks"),
+			FighterProductionCost:                  r.readInt("FighterProductionCost"),
+			MaxFacilityCapturePoints:               r.readFloat64("MaxFacilityCapturePoints"),
+			FacilityCapturePointsPerVehiclePerTick: r.readFloat64("FacilityCapturePointsPerVehiclePerTick"),
+			FacilityWidth:                          r.readFloat64("FacilityWidth"),
+			FacilityHeight:                         r.readFloat64("FacilityHeight"),
+			BaseTacticalNuclearStrikeCooldown:      r.readInt("BaseTacticalNuclearStrikeCooldown"),
+			TacticalNuclearStrikeCooldownDecreasePerControlCenter: r.readInt("TacticalNuclearStrikeCooldownDecreasePerControlCenter"),
+			TacticalNuclearStrikeMaxDamage:                        r.readFloat64("TacticalNuclearStrikeMaxDamage"),
+			TacticalNuclearStrikeRadius:                           r.readFloat64("TacticalNuclearStrikeRadius"),
+			TacticalNuclearStrikeDelay:                            r.readInt("TacticalNuclearStrikeDelay"),
+		}
+	}
+
+	r.game = g
+	return g, r.err
+}
+
+func (r *Reader) ReadContext(pc *PlayerContext) error {
+	switch m := r.readOpcode(); m {
+	case Message_GameOver:
+		return ErrGameOver
+	case Message_PlayerContext:
+		if r.readBool("PlayerContext") {
+			if me := r.readPlayer(); me != nil {
+				*pc.Player = *me
+			}
+			r.readWorld(pc.World)
+		}
+		return r.err
+	default:
+		if r.err != nil {
+			return r.err
+		}
+		return &ProtocolError{Message: m, Err: ErrWrongType}
+	}
+}
+
+func (r *Reader) ReadMove() (*Move, error) {
+	if err := r.Expect(Message_Move); err != nil {
+		return nil, err
+	}
+
+	if !r.readBool("Move") {
+		return nil, r.err
+	}
+
+	m := new(Move)
+	m.Action = ActionType(r.readByte("Action"))
+	m.Group = r.readInt("Group")
+	m.Left = r.readFloat64("Left")
+	m.Top = r.readFloat64("Top")
+	m.Right = r.readFloat64("Right")
+	m.Bottom = r.readFloat64("Bottom")
+	m.X = r.readFloat64("X")
+	m.Y = r.readFloat64("Y")
+	m.Angle = r.readFloat64("Angle")
+	m.Factor = r.readFloat64("Factor")
+	m.MaxSpeed = r.readFloat64("MaxSpeed")
+	m.MaxAngularSpeed = r.readFloat64("MaxAngularSpeed")
+	m.Type = VehicleType(r.readByte("Type"))
+	m.FacilityId = r.readInt64("FacilityId")
+	m.VehicleId = r.readInt64("VehicleId")
+
+	return m, r.err
+}
+
+func (r *Reader) readPlayer() *Player {
+	switch r.readByte("Player") {
+	case 0:
+		return nil
+	case 127:
+		return r.players[r.readInt64("Player.Id")]
+	default:
+		p := new(Player)
+		p.Id = r.readInt64("Player.Id")
+		p.Me = r.readBool("Player.Me")
+		p.StrategyCrashed = r.readBool("Player.StrategyCrashed")
+		p.Score = r.readInt("Player.Score")
+		p.RemainingActionCooldownTicks = r.readInt("Player.RemainingActionCooldownTicks")
+		p.RemainingNuclearStrikeCooldownTicks = r.readInt("Player.RemainingNuclearStrikeCooldownTicks")
+		p.NextNuclearStrikeVehicleId = r.readInt64("Player.NextNuclearStrikeVehicleId")
+		p.NextNuclearStrikeTickIndex = r.readInt("Player.NextNuclearStrikeTickIndex")
+		p.NextNuclearStrikeX = r.readFloat64("Player.NextNuclearStrikeX")
+		p.NextNuclearStrikeY = r.readFloat64("Player.NextNuclearStrikeY")
+
+		if r.err != nil {
+			return nil
+		}
+
+		r.players[p.Id] = p
+
+		return p
+	}
+}
+
+func (r *Reader) readWorld(w *World) {
+	if r.readBool("World") {
+		w.TickIndex = r.readInt("World.TickIndex")
+		w.TickCount = r.readInt("World.TickCount")
+		w.Width = r.readFloat64("World.Width")
+		w.Height = r.readFloat64("World.Height")
+		w.Players = r.readPlayers()
+		w.NewVehicles = r.readVehicles()
+		w.VehicleUpdates = r.readVehiclesUpdate()
+
+		if w.TickIndex == 0 {
+			w.TerrainByCellXY = r.readTerrains()
+			w.WeatherByCellXY = r.readWeather()
+		}
+
+		w.Facilities = r.readFacilities()
+
+		if r.err != nil {
+			return
+		}
+
+		if w.Vehicles == nil {
+			w.Vehicles = NewVehicleRegistry()
+		}
+		w.Vehicles.Update(w)
+
+		if r.game != nil {
+			if w.Map == nil {
+				w.Map = NewTerrainMap(r.game)
+			}
+			w.Map.Update(w)
+		}
+	}
+}
+
+func (r *Reader) readWeather() (weather [][]Weather) {
+	for i := r.readInt("World.WeatherByCellXY"); i > 0 && r.err == nil; i-- {
+		var slice []Weather
+		for j := r.readInt("World.WeatherByCellXY"); j > 0 && r.err == nil; j-- {
+			slice = append(slice, Weather(r.readByte("World.WeatherByCellXY")))
+		}
+		weather = append(weather, slice)
+	}
+
+	return
+}
+
+func (r *Reader) readTerrains() (terrain [][]Terrain) {
+	for i := r.readInt("World.TerrainByCellXY"); i > 0 && r.err == nil; i-- {
+		var slice []Terrain
+		for j := r.readInt("World.TerrainByCellXY"); j > 0 && r.err == nil; j-- {
+			slice = append(slice, Terrain(r.readByte("World.TerrainByCellXY")))
+		}
+		terrain = append(terrain, slice)
+	}
+	return
+}
+
+func (r *Reader) readFacility() *Facility {
+	switch r.readByte("Facility") {
+	case 0:
+		return nil
+	case 127:
+		return r.facilities[r.readInt64("Facility.Id")]
+	default:
+		f := new(Facility)
+		f.Id = r.readInt64("Facility.Id")
+		f.FacilityType = FacilityType(r.readByte("Facility.FacilityType"))
+		f.OwnerPlayerId = r.readInt64("Facility.OwnerPlayerId")
+		f.Left = r.readFloat64("Facility.Left")
+		f.Top = r.readFloat64("Facility.Top")
+		f.CapturePoints = r.readFloat64("Facility.CapturePoints")
+		f.VehicleType = VehicleType(r.readByte("Facility.VehicleType"))
+		f.ProductionProgress = r.readInt("Facility.ProductionProgress")
+
+		if r.err != nil {
+			return nil
+		}
+
+		r.facilities[f.Id] = f
+
+		return f
+	}
+}
+
+func (r *Reader) readVehicleUpdate() *VehicleUpdate {
+	if r.readBool("VehicleUpdate") {
+		v := new(VehicleUpdate)
+		v.Id = r.readInt64("VehicleUpdate.Id")
+		v.X = r.readFloat64("VehicleUpdate.X")
+		v.Y = r.readFloat64("VehicleUpdate.Y")
+		v.Durability = r.readInt("VehicleUpdate.Durability")
+		v.RemainingAttackCooldownTicks = r.readInt("VehicleUpdate.RemainingAttackCooldownTicks")
+		v.Selected = r.readBool("VehicleUpdate.Selected")
+		v.Groups = r.readIntArray("VehicleUpdate.Groups")
+
+		return v
+	}
+
+	return nil
+}
+
+func (r *Reader) readNewVehicle() *Vehicle {
+	if r.readBool("Vehicle") {
+		v := new(Vehicle)
+		v.Id = r.readInt64("Vehicle.Id")
+		v.X = r.readFloat64("Vehicle.X")
+		v.Y = r.readFloat64("Vehicle.Y")
+		v.Radius = r.readFloat64("Vehicle.Radius")
+		v.PlayerId = r.readInt64("Vehicle.PlayerId")
+		v.Durability = r.readInt("Vehicle.Durability")
+		v.MaxDurability = r.readInt("Vehicle.MaxDurability")
+		v.MaxSpeed = r.readFloat64("Vehicle.MaxSpeed")
+		v.VisionRange = r.readFloat64("Vehicle.VisionRange")
+		v.SquaredVisionRange = r.readFloat64("Vehicle.SquaredVisionRange")
+		v.GroundAttackRange = r.readFloat64("Vehicle.GroundAttackRange")
+		v.SquaredGroundAttackRange = r.readFloat64("Vehicle.SquaredGroundAttackRange")
+		v.AerialAttackRange = r.readFloat64("Vehicle.AerialAttackRange")
+		v.SquaredAerialAttackRange = r.readFloat64("Vehicle.SquaredAerialAttackRange")
+		v.GroundDamage = r.readInt("Vehicle.GroundDamage")
+		v.AerialDamage = r.readInt("Vehicle.AerialDamage")
+		v.GroundDefence = r.readInt("Vehicle.GroundDefence")
+		v.AerialDefence = r.readInt("Vehicle.AerialDefence")
+		v.AttackCooldownTicks = r.readInt("Vehicle.AttackCooldownTicks")
+		v.RemainingAttackCooldownTicks = r.readInt("Vehicle.RemainingAttackCooldownTicks")
+		v.Type = VehicleType(r.readByte("Vehicle.Type"))
+		v.Aerial = r.readBool("Vehicle.Aerial")
+		v.Selected = r.readBool("Vehicle.Selected")
+		v.Groups = r.readIntArray("Vehicle.Groups")
+
+		return v
+	}
+
+	return nil
+}
+
+func (r *Reader) readVehiclesUpdate() (updates []*VehicleUpdate) {
+	for l := r.readInt("World.VehicleUpdates"); l > 0 && r.err == nil; l-- {
+		if v := r.readVehicleUpdate(); v != nil {
+			updates = append(updates, v)
+		}
+	}
+	return
+}
+
+func (r *Reader) readFacilities() (facilities []*Facility) {
+	if l := r.readInt("World.Facilities"); l > 0 {
+		for ; l > 0 && r.err == nil; l-- {
+			if f := r.readFacility(); f != nil {
+				facilities = append(facilities, f)
+			}
+		}
+	} else {
+		for _, f := range r.facilities {
+			facilities = append(facilities, f)
+		}
+	}
+
+	return
+}
+
+func (r *Reader) readVehicles() (vehicles []*Vehicle) {
+	for l := r.readInt("World.NewVehicles"); l > 0 && r.err == nil; l-- {
+		if v := r.readNewVehicle(); v != nil {
+			vehicles = append(vehicles, v)
+		}
+	}
+	return
+}
+
+func (r *Reader) readPlayers() (players []*Player) {
+	if l := r.readInt("World.Players"); l > 0 {
+		for ; l > 0 && r.err == nil; l-- {
+			if p := r.readPlayer(); p != nil {
+				players = append(players, p)
+			}
+		}
+	} else {
+		for _, p := range r.players {
+			players = append(players, p)
+		}
+	}
+
+	return
+}
+
+func (r *Reader) ReadTeamSize() (int, error) {
+	if err := r.Expect(Message_TeamSize); err != nil {
+		return 0, err
+	}
+	size := r.readInt("TeamSize")
+	return size, r.err
+}
+
+// fail records the first error together with the message and field that
+// were being processed.
+func (r *Reader) fail(field string, err error) {
+	if r.err == nil {
+		r.err = &ProtocolError{Message: r.message, Field: field, Err: err}
+	}
+}
+
+func (r *Reader) ReadToken() (string, error) {
+	if err := r.Expect(Message_AuthenticationToken); err != nil {
+		return "", err
+	}
+	token := r.readString("Token")
+	return token, r.err
+}
+
+func (r *Reader) ReadProtocolVersion() (int, error) {
+	if err := r.Expect(Message_ProtocolVersion); err != nil {
+		return 0, err
+	}
+	version := r.readInt("Version")
+	return version, r.err
+}
+
+func (r *Reader) readOpcode() MessageType {
+	r.message = 0
+	r.message = MessageType(r.readByte("opcode"))
+	return r.message
+}
+
+// Expect reads the opcode of the next message and fails unless it is m.
+func (r *Reader) Expect(m MessageType) error {
+	if got := r.readOpcode(); r.err == nil && got != m {
+		r.message = m
+		r.fail("", fmt.Errorf("%w: got %v", ErrWrongType, got))
+	}
+	r.message = m
+	return r.err
+}
+
+func (r *Reader) readIntArray(field string) []int {
+	var arr []int
+	if ln := r.readInt(field); ln > 0 {
+		for ; ln > 0 && r.err == nil; ln-- {
+			arr = append(arr, r.readInt(field))
+		}
+	}
+	return arr
+}
+
+func (r *Reader) readInt(field string) int {
+	var v int32
+	if r.err == nil {
+		if err := binary.Read(r.r, ByteOrder, &v); err != nil {
+			r.fail(field, err)
+		}
+	}
+	return int(v)
+}
+
+func (r *Reader) readInt64(field string) int64 {
+	var v int64
+	if r.err == nil {
+		if err := binary.Read(r.r, ByteOrder, &v); err != nil {
+			r.fail(field, err)
+		}
+	}
+	return v
+}
+
+func (r *Reader) readFloat64(field string) float64 {
+	var v float64
+	if r.err == nil {
+		if err := binary.Read(r.r, ByteOrder, &v); err != nil {
+			r.fail(field, err)
+		}
+	}
+	return v
+}
+
+func (r *Reader) readBool(field string) bool {
+	return r.readByte(field) != 0
+}
+
+func (r *Reader) readByte(field string) byte {
+	if r.err != nil {
+		return 0
+	}
+	b, err := r.r.ReadByte()
+	if err != nil {
+		r.fail(field, err)
+	}
+	return b
+}
+
+func (r *Reader) readString(field string) string {
+	return string(r.readBytes(field))
+}
+
+func (r *Reader) readBytes(field string) []byte {
+	l := r.readInt(field)
+	if r.err != nil || l < 0 {
+		return nil
+	}
+	b := make([]byte, l)
+	for i := range b {
+		b[i] = r.readByte(field)
+	}
+	return b
+}
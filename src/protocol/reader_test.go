@@ -0,0 +1,90 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	. "model"
+	"testing"
+)
+
+func encodeGame(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := NewWriter(bufio.NewWriter(&buf)).WriteGame(DefaultGame(1)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadTruncatedGame(t *testing.T) {
+	data := encodeGame(t)
+
+	for _, n := range []int{2, 10, len(data) / 2, len(data) - 1} {
+		r := NewReader(bufio.NewReader(bytes.NewReader(data[:n])))
+		g, err := r.ReadGame()
+
+		var perr *ProtocolError
+		if !errors.As(err, &perr) {
+			t.Errorf("%d of %d bytes: got %v, %v, want a *ProtocolError", n, len(data), g, err)
+			continue
+		}
+		if perr.Message != Message_GameContext || perr.Field == "" || perr.Field == "opcode" {
+			t.Errorf("%d of %d bytes: error in message %v, field %q", n, len(data), perr.Message, perr.Field)
+		}
+		if perr.Err != io.EOF && perr.Err != io.ErrUnexpectedEOF {
+			t.Errorf("%d of %d bytes: cause %v, want an EOF", n, len(data), perr.Err)
+		}
+		if IsEndOfStream(err) {
+			t.Errorf("%d of %d bytes: truncated message taken for the end of the stream", n, len(data))
+		}
+	}
+}
+
+func TestReadErrorIsSticky(t *testing.T) {
+	game := encodeGame(t)
+	buf := bytes.NewBuffer(append([]byte(nil), game[:len(game)/2]...))
+
+	r := NewReader(bufio.NewReader(buf))
+	_, first := r.ReadGame()
+	if first == nil {
+		t.Fatal("truncated game read without error")
+	}
+
+	// A whole game arrives later, but the reader doesn't resync on it.
+	buf.Write(game)
+	if _, err := r.ReadGame(); err != first {
+		t.Errorf("second read returned %v, want the first error %v", err, first)
+	}
+	if err := r.Err(); err != first {
+		t.Errorf("Err() = %v, want %v", err, first)
+	}
+}
+
+func TestReadWrongMessageType(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(bufio.NewWriter(&buf)).WriteMove(MoveBy(1, 2, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewReader(bufio.NewReader(&buf)).ReadGame()
+	var perr *ProtocolError
+	if !errors.As(err, &perr) || !errors.Is(err, ErrWrongType) {
+		t.Fatalf("got %v, want a *ProtocolError wrapping ErrWrongType", err)
+	}
+	if perr.Message != Message_GameContext {
+		t.Errorf("error in message %v, want %v", perr.Message, Message_GameContext)
+	}
+}
+
+func TestReadEndOfStream(t *testing.T) {
+	data := encodeGame(t)
+	r := NewReader(bufio.NewReader(bytes.NewReader(data)))
+	if _, err := r.ReadGame(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadGame(); !IsEndOfStream(err) {
+		t.Errorf("read past the last message returned %v, want the end of the stream", err)
+	}
+}
@@ -0,0 +1,174 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// A recording is a sequence of frames. Every frame holds exactly one protocol
+// message as it went over the wire: the direction byte, the int32 length of
+// the message and the message bytes.
+type FrameDirection byte
+
+const (
+	Frame_Inbound FrameDirection = iota + 1
+	Frame_Outbound
+)
+
+type Frame struct {
+	Direction FrameDirection
+	Data      []byte
+}
+
+// Recorder tees the messages passing through a Reader and a Writer into a
+// recording file. Recording failures don't interrupt the game, the first one
+// is returned by Close.
+type Recorder struct {
+	file *os.File
+	w    *bufio.Writer
+
+	in  bytes.Buffer
+	out bytes.Buffer
+	err error
+}
+
+func CreateRecording(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (r *Recorder) Reader(src ByteReader) ByteReader {
+	return &recordingReader{ByteReader: src, buf: &r.in}
+}
+
+func (r *Recorder) Writer(dst MessageWriter) MessageWriter {
+	return &recordingWriter{MessageWriter: dst, r: r}
+}
+
+// EndInbound stores the inbound message read so far as a frame. Callers
+// invoke it before reading the opcode of the next message, since only they
+// know where inbound messages start.
+func (r *Recorder) EndInbound() {
+	r.writeFrame(Frame_Inbound, &r.in)
+}
+
+// endOutbound stores the outbound message written since the last flush. The
+// file is flushed too, so a recording survives a crash of the strategy.
+func (r *Recorder) endOutbound() {
+	r.EndInbound()
+	r.writeFrame(Frame_Outbound, &r.out)
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *Recorder) writeFrame(dir FrameDirection, b *bytes.Buffer) {
+	defer b.Reset()
+
+	if b.Len() == 0 || r.err != nil {
+		return
+	}
+
+	var header [5]byte
+	header[0] = byte(dir)
+	ByteOrder.PutUint32(header[1:], uint32(b.Len()))
+
+	if _, err := r.w.Write(header[:]); err != nil {
+		r.err = err
+		return
+	}
+	if _, err := r.w.Write(b.Bytes()); err != nil {
+		r.err = err
+	}
+}
+
+func (r *Recorder) Close() error {
+	r.EndInbound()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+type recordingReader struct {
+	ByteReader
+	buf *bytes.Buffer
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.ByteReader.Read(p)
+	r.buf.Write(p[:n])
+	return n, err
+}
+
+func (r *recordingReader) ReadByte() (byte, error) {
+	b, err := r.ByteReader.ReadByte()
+	if err == nil {
+		r.buf.WriteByte(b)
+	}
+	return b, err
+}
+
+type recordingWriter struct {
+	MessageWriter
+	r *Recorder
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	n, err := w.MessageWriter.Write(p)
+	w.r.out.Write(p[:n])
+	return n, err
+}
+
+func (w *recordingWriter) WriteByte(b byte) error {
+	err := w.MessageWriter.WriteByte(b)
+	if err == nil {
+		w.r.out.WriteByte(b)
+	}
+	return err
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	n, err := w.MessageWriter.WriteString(s)
+	w.r.out.WriteString(s[:n])
+	return n, err
+}
+
+func (w *recordingWriter) Flush() error {
+	err := w.MessageWriter.Flush()
+	w.r.endOutbound()
+	return err
+}
+
+// ReadRecording loads all frames of a recording file.
+func ReadRecording(path string) ([]*Frame, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var frames []*Frame
+	for len(data) > 0 {
+		if len(data) < 5 {
+			return frames, fmt.Errorf("recording %s: truncated frame header", path)
+		}
+
+		n := int(ByteOrder.Uint32(data[1:5]))
+		if len(data)-5 < n {
+			return frames, fmt.Errorf("recording %s: truncated frame", path)
+		}
+
+		frames = append(frames, &Frame{Direction: FrameDirection(data[0]), Data: data[5 : 5+n]})
+		data = data[5+n:]
+	}
+
+	return frames, nil
+}
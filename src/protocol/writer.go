@@ -0,0 +1,413 @@
+package protocol
+
+import (
+	"encoding/binary"
+	. "model"
+)
+
+// Writer encodes protocol messages. Like Reader it keeps the first I/O error
+// and turns all further writes into no-ops. Every message is flushed as soon
+// as it is complete.
+type Writer struct {
+	w MessageWriter
+
+	message MessageType
+	err     error
+}
+
+func NewWriter(w MessageWriter) *Writer {
+	return &Writer{w: w}
+}
+
+// Err returns the first error met by the writer.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) fail(field string, err error) {
+	if w.err == nil {
+		w.err = &ProtocolError{Message: w.message, Field: field, Err: err}
+	}
+}
+
+func (w *Writer) WriteToken(token string) error {
+	w.writeOpcode(Message_AuthenticationToken)
+	w.writeString("Token", token)
+	return w.flush()
+}
+
+func (w *Writer) WriteProtocolVersion(version int) error {
+	w.writeOpcode(Message_ProtocolVersion)
+	w.writeInt("Version", version)
+	return w.flush()
+}
+
+func (w *Writer) WriteMove(m *Move) error {
+	w.writeOpcode(Message_Move)
+
+	if m == nil {
+		w.writeBool("Move", false)
+	} else {
+		w.writeBool("Move", true)
+
+		w.writeByte("Action", byte(m.Action))
+		w.writeInt("Group", m.Group)
+		w.writeFloat64("Left", m.Left)
+		w.writeFloat64("Top", m.Top)
+		w.writeFloat64("Right", m.Right)
+		w.writeFloat64("Bottom", m.Bottom)
+		w.writeFloat64("X", m.X)
+		w.writeFloat64("Y", m.Y)
+		w.writeFloat64("Angle", m.Angle)
+		w.writeFloat64("Factor", m.Factor)
+		w.writeFloat64("MaxSpeed", m.MaxSpeed)
+		w.writeFloat64("MaxAngularSpeed", m.MaxAngularSpeed)
+		w.writeByte("Type", byte(m.Type))
+		w.writeInt64("FacilityId", m.FacilityId)
+		w.writeInt64("VehicleId", m.VehicleId)
+	}
+
+	return w.flush()
+}
+
+func (w *Writer) WriteTeamSize(size int) error {
+	w.writeOpcode(Message_TeamSize)
+	w.writeInt("TeamSize", size)
+	return w.flush()
+}
+
+func (w *Writer) WriteGame(g *Game) error {
+	w.writeOpcode(Message_GameContext)
+
+	w.writeBool("Game", g != nil)
+	if g != nil {
+		w.writeInt64("RandomSeed", g.RandomSeed)
+		w.writeInt("TickCount", g.TickCount)
+		w.writeFloat64("WorldWidth", g.WorldWidth)
+		w.writeFloat64("WorldHeight", g.WorldHeight)
+		w.writeBool("FogOfWarEnabled", g.FogOfWarEnabled)
+		w.writeInt("VictoryScore", g.VictoryScore)
+		w.writeInt("FacilityCaptureScore", g.FacilityCaptureScore)
+		w.writeInt("VehicleEliminationScore", g.VehicleEliminationScore)
+		w.writeInt("ActionDetectionInterval", g.ActionDetectionInterval)
+		w.writeInt("BaseActionCount", g.BaseActionCount)
+		w.writeInt("AdditionalActionCountPerControlCenter", g.AdditionalActionCountPerControlCenter)
+		w.writeInt("MaxUnitGroup", g.MaxUnitGroup)
+		w.writeInt("TerrainWeatherMapColumnCount", g.TerrainWeatherMapColumnCount)
+		w.writeInt("TerrainWeatherMapRowCount", g.TerrainWeatherMapRowCount)
+		w.writeFloat64("PlainTerrainVisionFactor", g.PlainTerrainVisionFactor)
+		w.writeFloat64("PlainTerrainStealthFactor", g.PlainTerrainStealthFactor)
+		w.writeFloat64("PlainTerrainSpeedFactor", g.PlainTerrainSpeedFactor)
+		w.writeFloat64("SwampTerrainVisionFactor", g.SwampTerrainVisionFactor)
+		w.writeFloat64("SwampTerrainStealthFactor", g.SwampTerrainStealthFactor)
+		w.writeFloat64("SwampTerrainSpeedFactor", g.SwampTerrainSpeedFactor)
+		w.writeFloat64("ForestTerrainVisionFactor", g.ForestTerrainVisionFactor)
+		w.writeFloat64("ForestTerrainStealthFactor", g.ForestTerrainStealthFactor)
+		w.writeFloat64("ForestTerrainSpeedFactor", g.ForestTerrainSpeedFactor)
+		w.writeFloat64("ClearWeatherVisionFactor", g.ClearWeatherVisionFactor)
+		w.writeFloat64("ClearWeatherStealthFactor", g.ClearWeatherStealthFactor)
+		w.writeFloat64("ClearWeatherSpeedFactor", g.ClearWeatherSpeedFactor)
+		w.writeFloat64("CloudWeatherVisionFactor", g.CloudWeatherVisionFactor)
+		w.writeFloat64("CloudWeatherStealthFactor", g.CloudWeatherStealthFactor)
+		w.writeFloat64("CloudWeatherSpeedFactor", g.CloudWeatherSpeedFactor)
+		w.writeFloat64("RainWeatherVisionFactor", g.RainWeatherVisionFactor)
+		w.writeFloat64("RainWeatherStealthFactor", g.RainWeatherStealthFactor)
+		w.writeFloat64("RainWeatherSpeedFactor", g.RainWeatherSpeedFactor)
+		w.writeFloat64("VehicleRadius", g.VehicleRadius)
+		w.writeInt("TankDurability", g.TankDurability)
+		w.writeFloat64("TankSpeed", g.TankSpeed)
+		w.writeFloat64("TankVisionRange", g.TankVisionRange)
+		w.writeFloat64("TankGroundAttackRange", g.TankGroundAttackRange)
+		w.writeFloat64("TankAerialAttackRange", g.TankAerialAttackRange)
+		w.writeInt("TankGroundDamage", g.TankGroundDamage)
+		w.writeInt("TankAerialDamage", g.TankAerialDamage)
+		w.writeInt("TankGroundDefence", g.TankGroundDefence)
+		w.writeInt("TankAerialDefence", g.TankAerialDefence)
+		w.writeInt("TankAttackCooldownTicks", g.TankAttackCooldownTicks)
+		w.writeInt("TankProductionCost", g.TankProductionCost)
+		w.writeInt("IFVDurability", g.IFVDurability)
+		w.writeFloat64("IFVSpeed", g.IFVSpeed)
+		w.writeFloat64("IFVVisionRange", g.IFVVisionRange)
+		w.writeFloat64("IFVGroundAttackRange", g.IFVGroundAttackRange)
+		w.writeFloat64("IFVAerialAttackRange", g.IFVAerialAttackRange)
+		w.writeInt("IFVGroundDamage", g.IFVGroundDamage)
+		w.writeInt("IFVAerialDamage", g.IFVAerialDamage)
+		w.writeInt("IFVGroundDefence", g.IFVGroundDefence)
+		w.writeInt("IFVAerialDefence", g.IFVAerialDefence)
+		w.writeInt("IFVAttackCooldownTicks", g.IFVAttackCooldownTicks)
+		w.writeInt("IFVProductionCost", g.IFVProductionCost)
+		w.writeInt("ARRVDurability", g.ARRVDurability)
+		w.writeFloat64("ARRVSpeed", g.ARRVSpeed)
+		w.writeFloat64("ARRVVisionRange", g.ARRVVisionRange)
+		w.writeInt("ARRVGroundDefence", g.ARRVGroundDefence)
+		w.writeInt("ARRVAerialDefence", g.ARRVAerialDefence)
+		w.writeInt("ARRVProductionCost", g.ARRVProductionCost)
+		w.writeFloat64("ARRVRepairRange", g.ARRVRepairRange)
+		w.writeFloat64("ARRVRepairSpeed", g.ARRVRepairSpeed)
+		w.writeInt("HelicopterDurability", g.HelicopterDurability)
+		w.writeFloat64("HelicopterSpeed", g.HelicopterSpeed)
+		w.writeFloat64("HelicopterVisionRange", g.HelicopterVisionRange)
+		w.writeFloat64("HelicopterGroundAttackRange", g.HelicopterGroundAttackRange)
+		w.writeFloat64("HelicopterAerialAttackRange", g.HelicopterAerialAttackRange)
+		w.writeInt("HelicopterGroundDamage", g.HelicopterGroundDamage)
+		w.writeInt("HelicopterAerialDamage", g.HelicopterAerialDamage)
+		w.writeInt("HelicopterGroundDefence", g.HelicopterGroundDefence)
+		w.writeInt("HelicopterAerialDefence", g.HelicopterAerialDefence)
+		w.writeInt("HelicopterAttackCooldownTicks", g.HelicopterAttackCooldownTicks)
+		w.writeInt("HelicopterProductionCost", g.HelicopterProductionCost)
+		w.writeInt("FighterDurability", g.FighterDurability)
+		w.writeFloat64("FighterSpeed", g.FighterSpeed)
+		w.writeFloat64("FighterVisionRange", g.FighterVisionRange)
+		w.writeFloat64("FighterGroundAttackRange", g.FighterGroundAttackRange)
+		w.writeFloat64("FighterAerialAttackRange", g.FighterAerialAttackRange)
+		w.writeInt("FighterGroundDamage", g.FighterGroundDamage)
+		w.writeInt("FighterAerialDamage", g.FighterAerialDamage)
+		w.writeInt("FighterGroundDefence", g.FighterGroundDefence)
+		w.writeInt("FighterAerialDefence", g.FighterAerialDefence)
+		w.writeInt("FighterAttackCooldownTicks", g.FighterAttackCooldownTicks)
+		w.writeInt("FighterProductionCost", g.FighterProductionCost)
+		w.writeFloat64("MaxFacilityCapturePoints", g.MaxFacilityCapturePoints)
+		w.writeFloat64("FacilityCapturePointsPerVehiclePerTick", g.FacilityCapturePointsPerVehiclePerTick)
+		w.writeFloat64("FacilityWidth", g.FacilityWidth)
+		w.writeFloat64("FacilityHeight", g.FacilityHeight)
+		w.writeInt("BaseTacticalNuclearStrikeCooldown", g.BaseTacticalNuclearStrikeCooldown)
+		w.writeInt("TacticalNuclearStrikeCooldownDecreasePerControlCenter", g.TacticalNuclearStrikeCooldownDecreasePerControlCenter)
+		w.writeFloat64("TacticalNuclearStrikeMaxDamage", g.TacticalNuclearStrikeMaxDamage)
+		w.writeFloat64("TacticalNuclearStrikeRadius", g.TacticalNuclearStrikeRadius)
+		w.writeInt("TacticalNuclearStrikeDelay", g.TacticalNuclearStrikeDelay)
+	}
+
+	return w.flush()
+}
+
+func (w *Writer) WriteGameOver() error {
+	w.writeOpcode(Message_GameOver)
+	return w.flush()
+}
+
+// WriteContext encodes a player context. Players and facilities are always
+// sent in full, the terrain and weather grids only on tick 0, mirroring what
+// Reader expects.
+func (w *Writer) WriteContext(pc *PlayerContext) error {
+	w.writeOpcode(Message_PlayerContext)
+
+	w.writeBool("PlayerContext", pc != nil)
+	if pc != nil {
+		w.writePlayer(pc.Player)
+		w.writeWorld(pc.World)
+	}
+
+	return w.flush()
+}
+
+func (w *Writer) writePlayer(p *Player) {
+	if p == nil {
+		w.writeByte("Player", 0)
+		return
+	}
+
+	w.writeByte("Player", 1)
+	w.writeInt64("Player.Id", p.Id)
+	w.writeBool("Player.Me", p.Me)
+	w.writeBool("Player.StrategyCrashed", p.StrategyCrashed)
+	w.writeInt("Player.Score", p.Score)
+	w.writeInt("Player.RemainingActionCooldownTicks", p.RemainingActionCooldownTicks)
+	w.writeInt("Player.RemainingNuclearStrikeCooldownTicks", p.RemainingNuclearStrikeCooldownTicks)
+	w.writeInt64("Player.NextNuclearStrikeVehicleId", p.NextNuclearStrikeVehicleId)
+	w.writeInt("Player.NextNuclearStrikeTickIndex", p.NextNuclearStrikeTickIndex)
+	w.writeFloat64("Player.NextNuclearStrikeX", p.NextNuclearStrikeX)
+	w.writeFloat64("Player.NextNuclearStrikeY", p.NextNuclearStrikeY)
+}
+
+func (w *Writer) writeWorld(wd *World) {
+	w.writeBool("World", wd != nil)
+	if wd == nil {
+		return
+	}
+
+	w.writeInt("World.TickIndex", wd.TickIndex)
+	w.writeInt("World.TickCount", wd.TickCount)
+	w.writeFloat64("World.Width", wd.Width)
+	w.writeFloat64("World.Height", wd.Height)
+
+	w.writeInt("World.Players", len(wd.Players))
+	for _, p := range wd.Players {
+		w.writePlayer(p)
+	}
+
+	w.writeInt("World.NewVehicles", len(wd.NewVehicles))
+	for _, v := range wd.NewVehicles {
+		w.writeNewVehicle(v)
+	}
+
+	w.writeInt("World.VehicleUpdates", len(wd.VehicleUpdates))
+	for _, v := range wd.VehicleUpdates {
+		w.writeVehicleUpdate(v)
+	}
+
+	if wd.TickIndex == 0 {
+		w.writeInt("World.TerrainByCellXY", len(wd.TerrainByCellXY))
+		for _, column := range wd.TerrainByCellXY {
+			w.writeInt("World.TerrainByCellXY", len(column))
+			for _, t := range column {
+				w.writeByte("World.TerrainByCellXY", byte(t))
+			}
+		}
+
+		w.writeInt("World.WeatherByCellXY", len(wd.WeatherByCellXY))
+		for _, column := range wd.WeatherByCellXY {
+			w.writeInt("World.WeatherByCellXY", len(column))
+			for _, t := range column {
+				w.writeByte("World.WeatherByCellXY", byte(t))
+			}
+		}
+	}
+
+	w.writeInt("World.Facilities", len(wd.Facilities))
+	for _, f := range wd.Facilities {
+		w.writeFacility(f)
+	}
+}
+
+func (w *Writer) writeNewVehicle(v *Vehicle) {
+	w.writeBool("Vehicle", v != nil)
+	if v == nil {
+		return
+	}
+
+	w.writeInt64("Vehicle.Id", v.Id)
+	w.writeFloat64("Vehicle.X", v.X)
+	w.writeFloat64("Vehicle.Y", v.Y)
+	w.writeFloat64("Vehicle.Radius", v.Radius)
+	w.writeInt64("Vehicle.PlayerId", v.PlayerId)
+	w.writeInt("Vehicle.Durability", v.Durability)
+	w.writeInt("Vehicle.MaxDurability", v.MaxDurability)
+	w.writeFloat64("Vehicle.MaxSpeed", v.MaxSpeed)
+	w.writeFloat64("Vehicle.VisionRange", v.VisionRange)
+	w.writeFloat64("Vehicle.SquaredVisionRange", v.SquaredVisionRange)
+	w.writeFloat64("Vehicle.GroundAttackRange", v.GroundAttackRange)
+	w.writeFloat64("Vehicle.SquaredGroundAttackRange", v.SquaredGroundAttackRange)
+	w.writeFloat64("Vehicle.AerialAttackRange", v.AerialAttackRange)
+	w.writeFloat64("Vehicle.SquaredAerialAttackRange", v.SquaredAerialAttackRange)
+	w.writeInt("Vehicle.GroundDamage", v.GroundDamage)
+	w.writeInt("Vehicle.AerialDamage", v.AerialDamage)
+	w.writeInt("Vehicle.GroundDefence", v.GroundDefence)
+	w.writeInt("Vehicle.AerialDefence", v.AerialDefence)
+	w.writeInt("Vehicle.AttackCooldownTicks", v.AttackCooldownTicks)
+	w.writeInt("Vehicle.RemainingAttackCooldownTicks", v.RemainingAttackCooldownTicks)
+	w.writeByte("Vehicle.Type", byte(v.Type))
+	w.writeBool("Vehicle.Aerial", v.Aerial)
+	w.writeBool("Vehicle.Selected", v.Selected)
+	w.writeIntArray("Vehicle.Groups", v.Groups)
+}
+
+func (w *Writer) writeVehicleUpdate(v *VehicleUpdate) {
+	w.writeBool("VehicleUpdate", v != nil)
+	if v == nil {
+		return
+	}
+
+	w.writeInt64("VehicleUpdate.Id", v.Id)
+	w.writeFloat64("VehicleUpdate.X", v.X)
+	w.writeFloat64("VehicleUpdate.Y", v.Y)
+	w.writeInt("VehicleUpdate.Durability", v.Durability)
+	w.writeInt("VehicleUpdate.RemainingAttackCooldownTicks", v.RemainingAttackCooldownTicks)
+	w.writeBool("VehicleUpdate.Selected", v.Selected)
+	w.writeIntArray("VehicleUpdate.Groups", v.Groups)
+}
+
+func (w *Writer) writeFacility(f *Facility) {
+	if f == nil {
+		w.writeByte("Facility", 0)
+		return
+	}
+
+	w.writeByte("Facility", 1)
+	w.writeInt64("Facility.Id", f.Id)
+	w.writeByte("Facility.FacilityType", byte(f.FacilityType))
+	w.writeInt64("Facility.OwnerPlayerId", f.OwnerPlayerId)
+	w.writeFloat64("Facility.Left", f.Left)
+	w.writeFloat64("Facility.Top", f.Top)
+	w.writeFloat64("Facility.CapturePoints", f.CapturePoints)
+	w.writeByte("Facility.VehicleType", byte(f.VehicleType))
+	w.writeInt("Facility.ProductionProgress", f.ProductionProgress)
+}
+
+func (w *Writer) writeIntArray(field string, arr []int) {
+	w.writeInt(field, len(arr))
+	for _, v := range arr {
+		w.writeInt(field, v)
+	}
+}
+
+func (w *Writer) writeOpcode(m MessageType) {
+	w.message = m
+	w.writeByte("opcode", byte(m))
+}
+
+func (w *Writer) writeBool(field string, b bool) {
+	if b {
+		w.writeByte(field, 1)
+	} else {
+		w.writeByte(field, 0)
+	}
+}
+
+func (w *Writer) writeByte(field string, v byte) {
+	if w.err == nil {
+		if err := w.w.WriteByte(v); err != nil {
+			w.fail(field, err)
+		}
+	}
+}
+
+func (w *Writer) writeInt(field string, v int) {
+	if w.err == nil {
+		if err := binary.Write(w.w, ByteOrder, int32(v)); err != nil {
+			w.fail(field, err)
+		}
+	}
+}
+
+func (w *Writer) writeInt64(field string, v int64) {
+	if w.err == nil {
+		if err := binary.Write(w.w, ByteOrder, v); err != nil {
+			w.fail(field, err)
+		}
+	}
+}
+
+func (w *Writer) writeFloat64(field string, v float64) {
+	if w.err == nil {
+		if err := binary.Write(w.w, ByteOrder, v); err != nil {
+			w.fail(field, err)
+		}
+	}
+}
+
+func (w *Writer) writeBytes(field string, v []byte) {
+	w.writeInt(field, len(v))
+	if w.err == nil {
+		if _, err := w.w.Write(v); err != nil {
+			w.fail(field, err)
+		}
+	}
+}
+
+func (w *Writer) writeString(field string, v string) {
+	w.writeInt(field, len(v))
+	if w.err == nil {
+		if _, err := w.w.WriteString(v); err != nil {
+			w.fail(field, err)
+		}
+	}
+}
+
+func (w *Writer) flush() error {
+	if w.err == nil {
+		if err := w.w.Flush(); err != nil {
+			w.fail("", err)
+		}
+	}
+	return w.err
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	. "model"
+	"protocol"
+	"strings"
+	"time"
+)
+
+// MoveMismatch is a tick where the replayed strategy produced a move that
+// differs from the recorded one. Recorded is nil if the recording has no move
+// for that tick.
+type MoveMismatch struct {
+	TickIndex int
+	Recorded  *Move
+	Replayed  *Move
+}
+
+type ReplayReport struct {
+	Ticks      int
+	Mismatches []*MoveMismatch
+
+	// Time sums up the time the strategy took.
+	Time *TimeReport
+}
+
+func (r *ReplayReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "replayed %d ticks, %d moves differ", r.Ticks, len(r.Mismatches))
+	if r.Time != nil {
+		fmt.Fprintf(&b, ", %v", r.Time)
+	}
+	for i, m := range r.Mismatches {
+		if i == 10 {
+			fmt.Fprintf(&b, "\n  ...")
+			break
+		}
+		fmt.Fprintf(&b, "\n  tick %d: recorded %+v, replayed %+v", m.TickIndex, m.Recorded, m.Replayed)
+	}
+	return b.String()
+}
+
+// Replay feeds the inbound messages of a recording through the protocol
+// decoder to s, without a server, and compares the moves s makes with
+// the recorded ones. A recording that ends without Message_GameOver, e.g.
+// because the strategy crashed, is replayed up to its last message. Like Run,
+// Replay times every tick against a TimeBudget of limit, 0 for none.
+func Replay(path string, s Strategy, limit time.Duration) (*ReplayReport, error) {
+	frames, err := protocol.ReadRecording(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var in bytes.Buffer
+	var recorded []*Move
+
+	for _, f := range frames {
+		switch {
+		case f.Direction == protocol.Frame_Inbound:
+			in.Write(f.Data)
+		case len(f.Data) > 0 && protocol.MessageType(f.Data[0]) == protocol.Message_Move:
+			m, err := protocol.NewReader(bufio.NewReader(bytes.NewReader(f.Data))).ReadMove()
+			if err != nil {
+				return nil, err
+			}
+			recorded = append(recorded, m)
+		}
+	}
+
+	r := protocol.NewReader(bufio.NewReader(&in))
+
+	if _, err := r.ReadTeamSize(); err != nil {
+		return nil, err
+	}
+	g, err := r.ReadGame()
+	if err != nil {
+		return nil, err
+	}
+
+	report := new(ReplayReport)
+	budget := NewTimeBudget(limit, g.TickCount)
+	pc := &PlayerContext{Player: new(Player), World: new(World)}
+
+	for i := 0; ; i++ {
+		if err := r.ReadContext(pc); err != nil {
+			report.Time = budget.Report()
+			if err == protocol.ErrGameOver || protocol.IsEndOfStream(err) {
+				return report, nil
+			}
+			return report, err
+		}
+
+		m := NewMove()
+		budget.Move(s, pc.Player, pc.World, g, m)
+		report.Ticks++
+
+		var rec *Move
+		if i < len(recorded) {
+			rec = recorded[i]
+		}
+		if rec == nil || *rec != *m {
+			report.Mismatches = append(report.Mismatches, &MoveMismatch{
+				TickIndex: pc.World.TickIndex,
+				Recorded:  rec,
+				Replayed:  m,
+			})
+		}
+	}
+}
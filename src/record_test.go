@@ -0,0 +1,79 @@
+package main
+
+import (
+	. "model"
+	"path/filepath"
+	"server"
+	"testing"
+)
+
+// recordedStrategy selects everything, moves it and then scales it, so that
+// the recording has a few different moves.
+func recordedStrategy(dx float64) Strategy {
+	return StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {
+		switch w.TickIndex {
+		case 0:
+			*m = *SelectRect(0, 0, w.Width, w.Height)
+		case 1:
+			*m = *MoveBy(dx, 50, 0)
+		case 10:
+			*m = *ScaleAround(200, 200, 0.5)
+		}
+	})
+}
+
+func TestRecordReplay(t *testing.T) {
+	g := DefaultGame(1)
+	g.TickCount = 20
+	cfg, done := serve(t, server.Simulate(g, StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {})))
+	cfg.RecordFile = filepath.Join(t.TempDir(), "game.rec")
+
+	if err := Connect(cfg, recordedStrategy(50)); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	// The same strategy makes the same moves, replayed as often as needed.
+	var reports []*ReplayReport
+	for i := 0; i < 2; i++ {
+		report, err := Replay(cfg.RecordFile, recordedStrategy(50), 0)
+		if err != nil {
+			t.Fatalf("Replay: %v", err)
+		}
+		reports = append(reports, report)
+	}
+	for i, r := range reports {
+		if r.Ticks != g.TickCount || len(r.Mismatches) != 0 || r.Time == nil || r.Time.Ticks != g.TickCount {
+			t.Errorf("replay %d: %v, want %d ticks and no mismatch", i, r, g.TickCount)
+		}
+	}
+
+	// A changed strategy is caught on the tick it changed.
+	report, err := Replay(cfg.RecordFile, recordedStrategy(60), 0)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(report.Mismatches) != 1 || report.Mismatches[0].TickIndex != 1 ||
+		*report.Mismatches[0].Recorded != *MoveBy(50, 50, 0) || *report.Mismatches[0].Replayed != *MoveBy(60, 50, 0) {
+		t.Errorf("changed strategy: %v, want a single mismatch on tick 1", report)
+	}
+
+	// The server replays the recording to a client, which sends the
+	// recorded moves again.
+	rec, err := server.OpenRecording(cfg.RecordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, done = serve(t, rec)
+	if err := Connect(cfg, recordedStrategy(50)); err != nil {
+		t.Fatalf("Connect to the recording: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Serve the recording: %v", err)
+	}
+	if len(rec.Moves) != g.TickCount || *rec.Moves[1] != *MoveBy(50, 50, 0) {
+		t.Errorf("recording served %d ticks, moved %+v on tick 1", len(rec.Moves), rec.Moves[1])
+	}
+}
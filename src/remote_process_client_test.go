@@ -0,0 +1,103 @@
+package main
+
+import (
+	. "model"
+	"net"
+	"server"
+	"testing"
+)
+
+// serve starts src on a local port and returns the configuration to connect
+// to it and the result of the game on the server side.
+func serve(t *testing.T, src server.Source) (*Config, <-chan error) {
+	t.Helper()
+
+	srv, err := server.Listen("127.0.0.1:0", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve() }()
+
+	cfg := DefaultConfig()
+	cfg.Host, cfg.Port, _ = net.SplitHostPort(srv.Addr().String())
+	cfg.LogLevel = LogLevel_Off
+	return cfg, done
+}
+
+func TestConnectScript(t *testing.T) {
+	g := DefaultGame(1)
+	var contexts []*PlayerContext
+	for tick := 0; tick < 3; tick++ {
+		p := &Player{Id: 1, Me: true, NextNuclearStrikeVehicleId: -1, NextNuclearStrikeTickIndex: -1}
+		w := &World{TickIndex: tick, TickCount: 3, Width: g.WorldWidth, Height: g.WorldHeight, Players: []*Player{p}}
+		contexts = append(contexts, &PlayerContext{Player: p, World: w})
+	}
+	script := server.NewScript(g, contexts...)
+	cfg, done := serve(t, script)
+
+	want := []*Move{SelectRect(0, 0, 512, 512), MoveBy(10, -20, 0.5), NewMove()}
+	var ticks []int
+	s := StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {
+		ticks = append(ticks, w.TickIndex)
+		*m = *want[w.TickIndex]
+	})
+
+	if err := Connect(cfg, s); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	if len(ticks) != 3 || ticks[0] != 0 || ticks[2] != 2 {
+		t.Errorf("strategy called on ticks %v, want [0 1 2]", ticks)
+	}
+	if len(script.Moves) != len(want) {
+		t.Fatalf("server received %d moves, want %d", len(script.Moves), len(want))
+	}
+	for i, m := range script.Moves {
+		if *m != *want[i] {
+			t.Errorf("move %d: server received %+v, want %+v", i, *m, *want[i])
+		}
+	}
+}
+
+func TestConnectSimulation(t *testing.T) {
+	g := DefaultGame(1)
+	g.TickCount = 30
+	simulation := server.Simulate(g, StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {}))
+	cfg, done := serve(t, simulation)
+
+	ticks := 0
+	var mine int
+	s := StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {
+		ticks++
+		mine = len(w.Vehicles.Mine())
+		switch w.TickIndex {
+		case 0:
+			*m = *SelectRect(0, 0, w.Width, w.Height)
+		case 1:
+			*m = *MoveBy(100, 100, 0)
+		}
+	})
+
+	if err := Connect(cfg, s); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	if ticks != g.TickCount {
+		t.Errorf("strategy called %d times, want %d", ticks, g.TickCount)
+	}
+	if mine == 0 {
+		t.Errorf("no vehicles of ours in the last tick")
+	}
+	if r := simulation.Result(); r.Ticks != g.TickCount || r.Crashed[0] {
+		t.Errorf("simulation result %+v, want %d ticks and no crash", r, g.TickCount)
+	}
+}
@@ -1,5 +1,18 @@
 package main
 
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
 func main() {
-	Start(New())
+	if err := Start(New()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
 }
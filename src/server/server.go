@@ -0,0 +1,122 @@
+// Package server is a fake local game server speaking protocol version 3. It
+// uses the encoders of package protocol, the same ones RemoteProcessClient
+// decodes with, so the client can be run end to end without the official
+// runner:
+//
+//	srv, err := server.Listen("127.0.0.1:0", server.Simulate(DefaultGame(1), opponent))
+//	...
+//	go srv.Serve()
+//	cfg := DefaultConfig()
+//	cfg.Host, cfg.Port, _ = net.SplitHostPort(srv.Addr().String())
+//	err = Connect(cfg, strategy)
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	. "model"
+	"net"
+	"protocol"
+)
+
+// Source provides the game the server plays with its client.
+type Source interface {
+	Game() *Game
+
+	// Next returns the context of the next tick. last is the move the client
+	// made in response to the previous context and nil before the first one.
+	// io.EOF means the game is over.
+	Next(last *Move) (*PlayerContext, error)
+}
+
+type Server struct {
+	// Token is the authentication token the client must send. An empty token
+	// accepts any client.
+	Token  string
+	Source Source
+
+	listener net.Listener
+}
+
+func Listen(addr string, src Source) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{Source: src, listener: l}, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+// Serve accepts a single client and plays one game with it. The Source is
+// closed when Serve returns if it implements io.Closer, whether the game
+// ended or the client went away.
+func (s *Server) Serve() error {
+	if c, ok := s.Source.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return s.ServeConn(conn)
+}
+
+// ServeConn plays one game over an established connection: the handshake,
+// the game context, one player context per tick answered by a move, and
+// finally Message_GameOver.
+func (s *Server) ServeConn(conn io.ReadWriter) error {
+	r := protocol.NewReader(bufio.NewReader(conn))
+	w := protocol.NewWriter(bufio.NewWriter(conn))
+
+	token, err := r.ReadToken()
+	if err != nil {
+		return err
+	}
+	if s.Token != "" && token != s.Token {
+		return fmt.Errorf("server: wrong authentication token %q", token)
+	}
+
+	version, err := r.ReadProtocolVersion()
+	if err != nil {
+		return err
+	}
+	if version != protocol.Version {
+		return fmt.Errorf("server: unsupported protocol version %d", version)
+	}
+
+	if err := w.WriteTeamSize(1); err != nil {
+		return err
+	}
+	if err := w.WriteGame(s.Source.Game()); err != nil {
+		return err
+	}
+
+	var last *Move
+	for {
+		pc, err := s.Source.Next(last)
+		if err == io.EOF {
+			return w.WriteGameOver()
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := w.WriteContext(pc); err != nil {
+			return err
+		}
+		if last, err = r.ReadMove(); err != nil {
+			return err
+		}
+	}
+}
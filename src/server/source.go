@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	. "model"
+	"protocol"
+	"sim"
+	"sync"
+)
+
+// Script serves a fixed list of contexts and keeps the moves it receives.
+type Script struct {
+	game     *Game
+	contexts []*PlayerContext
+	next     int
+
+	Moves []*Move
+}
+
+func NewScript(g *Game, contexts ...*PlayerContext) *Script {
+	return &Script{game: g, contexts: contexts}
+}
+
+func (s *Script) Game() *Game {
+	return s.game
+}
+
+func (s *Script) Next(last *Move) (*PlayerContext, error) {
+	if last != nil {
+		s.Moves = append(s.Moves, last)
+	}
+	if s.next >= len(s.contexts) {
+		return nil, io.EOF
+	}
+	s.next++
+	return s.contexts[s.next-1], nil
+}
+
+// Recording serves the game stored in a recording file made by the client.
+// The recorded contexts are decoded and encoded again, so serving a
+// recording also checks the encoders against the decoders.
+type Recording struct {
+	r    *protocol.Reader
+	game *Game
+	pc   *PlayerContext
+
+	Moves []*Move
+}
+
+func OpenRecording(path string) (*Recording, error) {
+	frames, err := protocol.ReadRecording(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var in bytes.Buffer
+	for _, f := range frames {
+		if f.Direction == protocol.Frame_Inbound {
+			in.Write(f.Data)
+		}
+	}
+
+	rec := &Recording{
+		r:  protocol.NewReader(bufio.NewReader(&in)),
+		pc: &PlayerContext{Player: new(Player), World: new(World)},
+	}
+
+	if _, err := rec.r.ReadTeamSize(); err != nil {
+		return nil, err
+	}
+	if rec.game, err = rec.r.ReadGame(); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+func (r *Recording) Game() *Game {
+	return r.game
+}
+
+func (r *Recording) Next(last *Move) (*PlayerContext, error) {
+	if last != nil {
+		r.Moves = append(r.Moves, last)
+	}
+
+	err := r.r.ReadContext(r.pc)
+	if err == protocol.ErrGameOver || protocol.IsEndOfStream(err) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.pc, nil
+}
+
+var errClosed = errors.New("server: simulation closed")
+
+// Simulation serves a game of the simulator in package sim. The client plays
+// the first side, opponent the second one.
+type Simulation struct {
+	sim      *sim.Simulator
+	contexts chan *PlayerContext
+	moves    chan *Move
+	quit     chan struct{}
+	close    sync.Once
+	started  bool
+	result   sim.Result
+}
+
+func Simulate(g *Game, opponent sim.Strategy) *Simulation {
+	s := &Simulation{
+		contexts: make(chan *PlayerContext),
+		moves:    make(chan *Move),
+		quit:     make(chan struct{}),
+	}
+	s.sim = sim.New(g, &remote{s}, opponent)
+	return s
+}
+
+func (s *Simulation) Game() *Game {
+	return s.sim.Game()
+}
+
+func (s *Simulation) Next(last *Move) (*PlayerContext, error) {
+	if !s.started {
+		s.started = true
+		go func() {
+			s.result = s.sim.Run()
+			close(s.contexts)
+		}()
+	} else {
+		if last == nil {
+			last = &Move{Action: Action_None}
+		}
+		select {
+		case s.moves <- last:
+		case <-s.quit:
+			return nil, errClosed
+		}
+	}
+
+	select {
+	case pc, ok := <-s.contexts:
+		if !ok {
+			return nil, io.EOF
+		}
+		return pc, nil
+	case <-s.quit:
+		return nil, errClosed
+	}
+}
+
+// Result returns the outcome of the game once Next has returned io.EOF.
+func (s *Simulation) Result() sim.Result {
+	return s.result
+}
+
+// Close stops waiting for the client and ends the game after the current
+// tick, so the goroutine running the simulator exits. Next returns an error
+// afterwards. Close may be called more than once.
+func (s *Simulation) Close() error {
+	s.close.Do(func() { close(s.quit) })
+	return nil
+}
+
+// remote is the simulator side of the client: it hands the context over to
+// the server and waits for the move the client answers with.
+type remote struct {
+	s *Simulation
+}
+
+// Move leaves m at Action_None and stops the simulator once the simulation
+// is closed.
+func (r *remote) Move(p *Player, w *World, g *Game, m *Move) {
+	select {
+	case r.s.contexts <- &PlayerContext{Player: p, World: w}:
+	case <-r.s.quit:
+		r.s.sim.Stop()
+		return
+	}
+
+	select {
+	case last := <-r.s.moves:
+		*m = *last
+	case <-r.s.quit:
+		r.s.sim.Stop()
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bufio"
+	. "model"
+	"net"
+	"protocol"
+	"testing"
+	"time"
+)
+
+type idle struct{}
+
+func (idle) Move(*Player, *World, *Game, *Move) {}
+
+// waitStopped fails the test unless the goroutine running the simulator of s
+// exits in time.
+func waitStopped(t *testing.T, s *Simulation) {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case _, ok := <-s.contexts:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("simulator still running after Close")
+		}
+	}
+}
+
+func TestSimulationClose(t *testing.T) {
+	s := Simulate(DefaultGame(1), idle{})
+	if _, err := s.Next(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Close()
+	s.Close()
+
+	if _, err := s.Next(NewMove()); err != errClosed {
+		t.Errorf("Next after Close returned %v, want %v", err, errClosed)
+	}
+	waitStopped(t, s)
+	if r := s.Result(); r.Ticks >= s.Game().TickCount {
+		t.Errorf("game went on for %d ticks after Close", r.Ticks)
+	}
+}
+
+func TestServeClosesSimulationOnDisconnect(t *testing.T) {
+	s := Simulate(DefaultGame(1), idle{})
+	srv, err := Listen("127.0.0.1:0", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve() }()
+
+	conn, err := net.Dial("tcp", srv.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := protocol.NewReader(bufio.NewReader(conn))
+	w := protocol.NewWriter(bufio.NewWriter(conn))
+
+	if err := w.WriteToken(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteProtocolVersion(protocol.Version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadTeamSize(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadGame(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ReadContext(&PlayerContext{Player: new(Player), World: new(World)}); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if err := <-served; err == nil {
+		t.Error("Serve returned no error after the client went away")
+	}
+	waitStopped(t, s)
+}
@@ -0,0 +1,14 @@
+package sim
+
+import . "model"
+
+// newVehicle builds a vehicle of type t with full durability from the game
+// constants.
+func newVehicle(g *Game, id, playerId int64, t VehicleType, x, y float64) *Vehicle {
+	v := g.NewVehicle(t)
+	v.Id = id
+	v.PlayerId = playerId
+	v.X = x
+	v.Y = y
+	return v
+}
@@ -0,0 +1,148 @@
+package sim
+
+import (
+	"math"
+	. "model"
+)
+
+const (
+	armySlotSize    = 74.0
+	armySlotOffset  = 18.0
+	armySpacing     = 6.0
+	armyColumnCount = 10
+	armyRowCount    = 10
+
+	facilityPairCount = 3
+)
+
+// generateMap fills the terrain and weather grids. The grids are point
+// symmetric around the map centre so neither side has an advantage.
+func (s *Simulator) generateMap() {
+	cols, rows := s.game.TerrainWeatherMapColumnCount, s.game.TerrainWeatherMapRowCount
+
+	s.terrain = make([][]Terrain, cols)
+	s.weather = make([][]Weather, cols)
+	for x := range s.terrain {
+		s.terrain[x] = make([]Terrain, rows)
+		s.weather[x] = make([]Weather, rows)
+	}
+
+	for x := 0; x < cols; x++ {
+		for y := 0; y < rows; y++ {
+			mx, my := cols-1-x, rows-1-y
+			if mx*rows+my < x*rows+y {
+				s.terrain[x][y] = s.terrain[mx][my]
+				s.weather[x][y] = s.weather[mx][my]
+				continue
+			}
+			s.terrain[x][y] = Terrain(s.pick(0.7, 0.15))
+			s.weather[x][y] = Weather(s.pick(0.7, 0.15))
+		}
+	}
+
+	s.terrainMap = NewTerrainMap(s.game)
+	s.terrainMap.Update(&World{
+		Width:           s.game.WorldWidth,
+		Height:          s.game.WorldHeight,
+		TerrainByCellXY: s.terrain,
+		WeatherByCellXY: s.weather,
+	})
+}
+
+// pick returns 0 with probability p0, 1 with probability p1 and 2 otherwise.
+func (s *Simulator) pick(p0, p1 float64) int {
+	switch r := s.rng.Float64(); {
+	case r < p0:
+		return 0
+	case r < p0+p1:
+		return 1
+	}
+	return 2
+}
+
+// generateFacilities places mirrored pairs of control centers and vehicle
+// factories outside of the starting areas.
+func (s *Simulator) generateFacilities() {
+	g := s.game
+	cols := int(g.WorldWidth / g.FacilityWidth)
+	rows := int(g.WorldHeight / g.FacilityHeight)
+	start := int(math.Ceil(3 * armySlotSize / g.FacilityWidth))
+
+	var cells [][2]int
+	for x := 0; x < cols; x++ {
+		for y := 0; y < rows; y++ {
+			mx, my := cols-1-x, rows-1-y
+			if x < start && y < start || mx < start && my < start {
+				continue
+			}
+			if mx*rows+my <= x*rows+y {
+				continue
+			}
+			cells = append(cells, [2]int{x, y})
+		}
+	}
+
+	s.rng.Shuffle(len(cells), func(i, j int) { cells[i], cells[j] = cells[j], cells[i] })
+
+	id := int64(1)
+	for i := 0; i < facilityPairCount && 2*i+1 < len(cells); i++ {
+		for j, t := range []FacilityType{Facility_ControlCenter, Facility_VehicleFactory} {
+			c := cells[2*i+j]
+			for _, xy := range [][2]int{c, {cols - 1 - c[0], rows - 1 - c[1]}} {
+				s.facilities = append(s.facilities, &Facility{
+					Id:            id,
+					FacilityType:  t,
+					OwnerPlayerId: -1,
+					Left:          float64(xy[0]) * g.FacilityWidth,
+					Top:           float64(xy[1]) * g.FacilityHeight,
+					VehicleType:   Vehicle_None,
+				})
+				id++
+			}
+		}
+	}
+}
+
+// generateArmies places 10x10 blocks of every vehicle type into the 3x3
+// starting slots of each player. Ground types never share a slot, aerial
+// types never share a slot either.
+func (s *Simulator) generateArmies() {
+	ground := s.rng.Perm(9)
+	aerial := s.rng.Perm(9)
+
+	slots := map[VehicleType]int{
+		Vehicle_Arrv:       ground[0],
+		Vehicle_Ifv:        ground[1],
+		Vehicle_Tank:       ground[2],
+		Vehicle_Fighter:    aerial[0],
+		Vehicle_Helicopter: aerial[1],
+	}
+
+	for _, p := range s.players {
+		for _, t := range []VehicleType{Vehicle_Arrv, Vehicle_Fighter, Vehicle_Helicopter, Vehicle_Ifv, Vehicle_Tank} {
+			slot := slots[t]
+			left := armySlotOffset + float64(slot%3)*armySlotSize
+			top := armySlotOffset + float64(slot/3)*armySlotSize
+
+			for i := 0; i < armyColumnCount; i++ {
+				for j := 0; j < armyRowCount; j++ {
+					x, y := left+float64(i)*armySpacing, top+float64(j)*armySpacing
+					if p.index == 1 {
+						x, y = s.game.WorldWidth-x, s.game.WorldHeight-y
+					}
+					s.addVehicle(p.Id, t, x, y)
+				}
+			}
+		}
+	}
+}
+
+// factors returns the vision, stealth and speed multipliers for a vehicle at
+// the given point.
+func (s *Simulator) factors(x, y float64, aerial bool) (vision, stealth, speed float64) {
+	return s.terrainMap.Factors(x, y, aerial)
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
@@ -0,0 +1,229 @@
+package sim
+
+import (
+	"math"
+	. "model"
+)
+
+// order is the movement a vehicle keeps performing across ticks until it is
+// finished or replaced by a newer one.
+type order struct {
+	action ActionType
+
+	// x, y is the destination for Action_Move and Action_Scale and the
+	// rotation centre for Action_Rotate.
+	x, y float64
+
+	angle           float64
+	maxSpeed        float64
+	maxAngularSpeed float64
+}
+
+// actionLimit is the number of actions the player may perform during any
+// ActionDetectionInterval ticks.
+func (s *Simulator) actionLimit(p *player) int {
+	return s.game.BaseActionCount + s.game.AdditionalActionCountPerControlCenter*s.controlCenters(p)
+}
+
+// actionCooldown drops actions that left the detection window and returns the
+// number of ticks until the next action is allowed.
+func (s *Simulator) actionCooldown(p *player) int {
+	interval := s.game.ActionDetectionInterval
+
+	recent := p.actions[:0]
+	for _, t := range p.actions {
+		if t > s.tick-interval {
+			recent = append(recent, t)
+		}
+	}
+	p.actions = recent
+
+	limit := s.actionLimit(p)
+	if len(p.actions) < limit {
+		return 0
+	}
+	return p.actions[len(p.actions)-limit] + interval - s.tick
+}
+
+func (s *Simulator) controlCenters(p *player) (n int) {
+	for _, f := range s.facilities {
+		if f.FacilityType == Facility_ControlCenter && f.OwnerPlayerId == p.Id {
+			n++
+		}
+	}
+	return
+}
+
+// applyMove performs the action of the player. Invalid actions are ignored
+// but, like on the real server, still count against the action limit.
+func (s *Simulator) applyMove(p *player, m *Move) {
+	if m == nil || m.Action == Action_None {
+		return
+	}
+
+	if s.actionCooldown(p) > 0 {
+		return
+	}
+	p.actions = append(p.actions, s.tick)
+
+	switch m.Action {
+	case Action_ClearAndSelect:
+		if s.validSelection(m) {
+			for _, v := range s.playerVehicles(p.Id) {
+				v.Selected = s.matches(v, m)
+			}
+		}
+	case Action_AddToSelection:
+		if s.validSelection(m) {
+			for _, v := range s.playerVehicles(p.Id) {
+				v.Selected = v.Selected || s.matches(v, m)
+			}
+		}
+	case Action_Deselect:
+		if s.validSelection(m) {
+			for _, v := range s.playerVehicles(p.Id) {
+				v.Selected = v.Selected && !s.matches(v, m)
+			}
+		}
+	case Action_Assign:
+		if s.validGroup(m.Group) {
+			for _, v := range s.selected(p) {
+				if !v.InGroup(m.Group) {
+					v.Groups = append(v.Groups, m.Group)
+				}
+			}
+		}
+	case Action_Dismiss:
+		if s.validGroup(m.Group) {
+			for _, v := range s.selected(p) {
+				v.Groups = removeGroup(v.Groups, m.Group)
+			}
+		}
+	case Action_Disband:
+		if s.validGroup(m.Group) {
+			for _, v := range s.playerVehicles(p.Id) {
+				v.Groups = removeGroup(v.Groups, m.Group)
+			}
+		}
+	case Action_Move:
+		if math.Abs(m.X) <= s.game.WorldWidth && math.Abs(m.Y) <= s.game.WorldHeight && m.MaxSpeed >= 0 {
+			for _, v := range s.selected(p) {
+				v.order = order{action: Action_Move, x: v.X + m.X, y: v.Y + m.Y, maxSpeed: m.MaxSpeed}
+			}
+		}
+	case Action_Rotate:
+		if s.validCentre(m) && math.Abs(m.Angle) <= math.Pi && m.MaxSpeed >= 0 &&
+			m.MaxAngularSpeed >= 0 && m.MaxAngularSpeed <= math.Pi {
+			for _, v := range s.selected(p) {
+				v.order = order{
+					action:          Action_Rotate,
+					x:               m.X,
+					y:               m.Y,
+					angle:           m.Angle,
+					maxSpeed:        m.MaxSpeed,
+					maxAngularSpeed: m.MaxAngularSpeed,
+				}
+			}
+		}
+	case Action_Scale:
+		if s.validCentre(m) && m.Factor >= 0.1 && m.Factor <= 10 && m.MaxSpeed >= 0 {
+			for _, v := range s.selected(p) {
+				v.order = order{
+					action:   Action_Scale,
+					x:        m.X + (v.X-m.X)*m.Factor,
+					y:        m.Y + (v.Y-m.Y)*m.Factor,
+					maxSpeed: m.MaxSpeed,
+				}
+			}
+		}
+	case Action_SetupVehicleProduction:
+		for _, f := range s.facilities {
+			if f.Id == m.FacilityId && f.FacilityType == Facility_VehicleFactory && f.OwnerPlayerId == p.Id {
+				f.VehicleType = m.Type
+				f.ProductionProgress = 0
+			}
+		}
+	case Action_TacticalNuclearStrike:
+		s.launchNuclearStrike(p, m)
+	}
+}
+
+func (s *Simulator) validSelection(m *Move) bool {
+	if m.Group != 0 {
+		return s.validGroup(m.Group)
+	}
+	return m.Left >= 0 && m.Left <= m.Right && m.Right <= s.game.WorldWidth &&
+		m.Top >= 0 && m.Top <= m.Bottom && m.Bottom <= s.game.WorldHeight
+}
+
+func (s *Simulator) validGroup(group int) bool {
+	return group >= 1 && group <= s.game.MaxUnitGroup
+}
+
+func (s *Simulator) validCentre(m *Move) bool {
+	return m.X >= -s.game.WorldWidth && m.X <= 2*s.game.WorldWidth &&
+		m.Y >= -s.game.WorldHeight && m.Y <= 2*s.game.WorldHeight
+}
+
+// matches reports whether the vehicle is caught by the selection parameters
+// of the move: either the group or the type filtered rectangle.
+func (s *Simulator) matches(v *vehicle, m *Move) bool {
+	if m.Group != 0 {
+		return v.InGroup(m.Group)
+	}
+	if m.Type != Vehicle_None && v.Type != m.Type {
+		return false
+	}
+	return v.X >= m.Left && v.X <= m.Right && v.Y >= m.Top && v.Y <= m.Bottom
+}
+
+func (s *Simulator) selected(p *player) []*vehicle {
+	var vehicles []*vehicle
+	for _, v := range s.playerVehicles(p.Id) {
+		if v.Selected {
+			vehicles = append(vehicles, v)
+		}
+	}
+	return vehicles
+}
+
+func removeGroup(groups []int, group int) []int {
+	kept := groups[:0]
+	for _, g := range groups {
+		if g != group {
+			kept = append(kept, g)
+		}
+	}
+	return kept
+}
+
+func (s *Simulator) launchNuclearStrike(p *player, m *Move) {
+	if p.RemainingNuclearStrikeCooldownTicks > 0 || p.NextNuclearStrikeTickIndex >= 0 {
+		return
+	}
+
+	v := s.byId[m.VehicleId]
+	if v == nil || v.dead || v.PlayerId != p.Id {
+		return
+	}
+	if m.X < 0 || m.X > s.game.WorldWidth || m.Y < 0 || m.Y > s.game.WorldHeight {
+		return
+	}
+
+	vision, _, _ := s.factors(v.X, v.Y, v.Aerial)
+	if r := v.VisionRange * vision; v.GetSquaredDistanceTo(m.X, m.Y) > r*r {
+		return
+	}
+
+	p.NextNuclearStrikeVehicleId = v.Id
+	p.NextNuclearStrikeTickIndex = s.tick + s.game.TacticalNuclearStrikeDelay
+	p.NextNuclearStrikeX = m.X
+	p.NextNuclearStrikeY = m.Y
+
+	cooldown := s.game.BaseTacticalNuclearStrikeCooldown -
+		s.game.TacticalNuclearStrikeCooldownDecreasePerControlCenter*s.controlCenters(p)
+	if cooldown < 0 {
+		cooldown = 0
+	}
+	p.RemainingNuclearStrikeCooldownTicks = cooldown
+}
@@ -0,0 +1,334 @@
+package sim
+
+import (
+	"math"
+	. "model"
+)
+
+const (
+	gridCellSize = 32.0
+	epsilon      = 1e-7
+)
+
+// damage lowers the durability of v and awards the elimination score to the
+// attacker if v is destroyed by an enemy.
+func (s *Simulator) damage(v *vehicle, amount int, by *player) {
+	if v.dead || amount <= 0 {
+		return
+	}
+
+	v.Durability -= amount
+	if v.Durability > 0 {
+		return
+	}
+
+	v.Durability = 0
+	v.dead = true
+
+	if by != nil && by.Id != v.PlayerId {
+		by.Score += s.game.VehicleEliminationScore
+	}
+}
+
+func (s *Simulator) detonateNuclearStrikes() {
+	for _, p := range s.players {
+		if p.NextNuclearStrikeTickIndex < 0 {
+			continue
+		}
+
+		spotter := s.byId[p.NextNuclearStrikeVehicleId]
+		alive := spotter != nil && !spotter.dead
+		if alive && p.NextNuclearStrikeTickIndex > s.tick {
+			continue
+		}
+
+		// The strike is cancelled if the spotter was destroyed in time.
+		if alive {
+			r := s.game.TacticalNuclearStrikeRadius
+			for _, v := range s.vehicles {
+				if d := v.GetDistanceTo(p.NextNuclearStrikeX, p.NextNuclearStrikeY); d <= r {
+					s.damage(v, int(s.game.TacticalNuclearStrikeMaxDamage*(1-d/r)), p)
+				}
+			}
+		}
+
+		p.NextNuclearStrikeVehicleId = -1
+		p.NextNuclearStrikeTickIndex = -1
+		p.NextNuclearStrikeX = 0
+		p.NextNuclearStrikeY = 0
+	}
+}
+
+// index returns a spatial index of the alive vehicles among the given ones.
+// It holds pointers to the embedded Vehicle, s.byId maps them back.
+func (s *Simulator) index(vehicles []*vehicle) *SpatialIndex {
+	index := NewSpatialIndex(s.game.WorldWidth, s.game.WorldHeight, gridCellSize)
+	for _, v := range vehicles {
+		if !v.dead {
+			index.Insert(&v.Vehicle)
+		}
+	}
+	return index
+}
+
+func (s *Simulator) moveVehicles() {
+	index := s.index(s.vehicles)
+
+	for _, v := range s.vehicles {
+		if v.dead || v.order.action == Action_None {
+			continue
+		}
+
+		x, y, done := s.nextPosition(v)
+		if done {
+			v.order = order{}
+		}
+
+		r := v.Radius
+		x = clamp(x, r, s.game.WorldWidth-r)
+		y = clamp(y, r, s.game.WorldHeight-r)
+
+		if (x != v.X || y != v.Y) && !s.collides(index, v, x, y) {
+			v.X, v.Y = x, y
+			index.Move(&v.Vehicle)
+		}
+	}
+}
+
+// nextPosition returns the position of v after one tick of its order and
+// whether the order is finished.
+func (s *Simulator) nextPosition(v *vehicle) (x, y float64, done bool) {
+	_, _, factor := s.factors(v.X, v.Y, v.Aerial)
+	speed := v.MaxSpeed * factor
+	if v.order.maxSpeed > 0 {
+		speed = math.Min(speed, v.order.maxSpeed)
+	}
+
+	switch o := &v.order; o.action {
+	case Action_Move, Action_Scale:
+		dx, dy := o.x-v.X, o.y-v.Y
+		d := math.Hypot(dx, dy)
+		if d <= speed+epsilon {
+			return o.x, o.y, true
+		}
+		return v.X + dx/d*speed, v.Y + dy/d*speed, false
+
+	case Action_Rotate:
+		dx, dy := v.X-o.x, v.Y-o.y
+		radius := math.Hypot(dx, dy)
+		if radius < epsilon || math.Abs(o.angle) < epsilon {
+			return v.X, v.Y, true
+		}
+
+		limit := speed / radius
+		if o.maxAngularSpeed > 0 {
+			limit = math.Min(limit, o.maxAngularSpeed)
+		}
+		step := math.Copysign(math.Min(math.Abs(o.angle), limit), o.angle)
+		o.angle -= step
+
+		sin, cos := math.Sincos(step)
+		return o.x + dx*cos - dy*sin, o.y + dx*sin + dy*cos, math.Abs(o.angle) < epsilon
+	}
+
+	return v.X, v.Y, true
+}
+
+// collides reports whether moving v to (x, y) makes it overlap another
+// vehicle. Ground vehicles collide with any ground vehicle, aerial ones only
+// with aerial vehicles of the same player. Moves that increase the distance
+// to an already overlapping vehicle are allowed so stuck units can separate.
+func (s *Simulator) collides(index *SpatialIndex, v *vehicle, x, y float64) bool {
+	blocking := index.Radius(x, y, 2*v.Radius, func(u *Vehicle) bool {
+		if u.Id == v.Id || u.Aerial != v.Aerial || v.Aerial && u.PlayerId != v.PlayerId {
+			return false
+		}
+		min := v.Radius + u.Radius
+		d := u.GetSquaredDistanceTo(x, y)
+		return d < min*min && d < u.GetSquaredDistanceTo(v.X, v.Y)
+	})
+	return len(blocking) > 0
+}
+
+// attack lets every vehicle whose cooldown is over hit the enemy it damages
+// the most. Damage is applied after all targets are chosen so the result
+// doesn't depend on the processing order.
+func (s *Simulator) attack() {
+	index := s.index(s.vehicles)
+	damage := make(map[*vehicle]int)
+	attackers := make(map[*vehicle]*player)
+
+	for _, a := range s.vehicles {
+		if a.dead || a.RemainingAttackCooldownTicks > 0 || a.GroundDamage == 0 && a.AerialDamage == 0 {
+			continue
+		}
+
+		var target *vehicle
+		best := 0
+		r := math.Max(a.GroundAttackRange, a.AerialAttackRange)
+		for _, u := range index.Radius(a.X, a.Y, r, NotOfPlayer(a.PlayerId)) {
+			dmg := Damage(&a.Vehicle, u)
+			if dmg == 0 || !InAttackRange(&a.Vehicle, u) {
+				continue
+			}
+
+			if t := s.byId[u.Id]; target == nil || dmg > best || dmg == best && betterTarget(a, t, target) {
+				target, best = t, dmg
+			}
+		}
+
+		if target != nil {
+			damage[target] += best
+			attackers[target] = s.players[1-s.playerIndex(target.PlayerId)]
+			a.RemainingAttackCooldownTicks = a.AttackCooldownTicks
+		}
+	}
+
+	for _, v := range s.vehicles {
+		if dmg, ok := damage[v]; ok {
+			s.damage(v, dmg, attackers[v])
+		}
+	}
+}
+
+// betterTarget breaks ties between equally damaged targets: the weakest one
+// first, then the closest one, then the one with the smaller id.
+func betterTarget(a, t, current *vehicle) bool {
+	if t.Durability != current.Durability {
+		return t.Durability < current.Durability
+	}
+	dt, dc := a.GetSquaredDistanceUnit(&t.Unit), a.GetSquaredDistanceUnit(&current.Unit)
+	if dt != dc {
+		return dt < dc
+	}
+	return t.Id < current.Id
+}
+
+// repair restores durability of vehicles standing next to a friendly ARRV.
+func (s *Simulator) repair() {
+	var arrvs []*vehicle
+	for _, v := range s.vehicles {
+		if !v.dead && v.Type == Vehicle_Arrv {
+			arrvs = append(arrvs, v)
+		}
+	}
+	index := s.index(arrvs)
+	r := s.game.ARRVRepairRange
+
+	for _, v := range s.vehicles {
+		if v.dead || v.Durability >= v.MaxDurability {
+			continue
+		}
+
+		repairers := index.Radius(v.X, v.Y, r, func(a *Vehicle) bool {
+			return a.Id != v.Id && a.PlayerId == v.PlayerId
+		})
+		if len(repairers) == 0 {
+			continue
+		}
+
+		v.repair += s.game.ARRVRepairSpeed
+		for v.repair >= 1 && v.Durability < v.MaxDurability {
+			v.Durability++
+			v.repair--
+		}
+		if v.Durability >= v.MaxDurability {
+			v.repair = 0
+		}
+	}
+}
+
+// captureFacilities moves capture points towards the player whose ground
+// vehicles stand inside the facility. Contested facilities don't change.
+func (s *Simulator) captureFacilities() {
+	max := s.game.MaxFacilityCapturePoints
+
+	for _, f := range s.facilities {
+		count := [2]int{}
+		for _, v := range s.vehicles {
+			if !v.dead && !v.Aerial && s.inside(f, v.X, v.Y) {
+				count[s.playerIndex(v.PlayerId)]++
+			}
+		}
+		if count[0] > 0 && count[1] > 0 || count[0] == count[1] {
+			continue
+		}
+
+		f.CapturePoints = clamp(f.CapturePoints+float64(count[0]-count[1])*s.game.FacilityCapturePointsPerVehiclePerTick, -max, max)
+
+		owner := f.OwnerPlayerId
+		switch {
+		case f.CapturePoints >= max:
+			owner = s.players[0].Id
+		case f.CapturePoints <= -max:
+			owner = s.players[1].Id
+		case owner == s.players[0].Id && f.CapturePoints <= 0,
+			owner == s.players[1].Id && f.CapturePoints >= 0:
+			owner = -1
+		}
+
+		if owner != f.OwnerPlayerId {
+			f.OwnerPlayerId = owner
+			f.VehicleType = Vehicle_None
+			f.ProductionProgress = 0
+			if owner != -1 {
+				s.players[s.playerIndex(owner)].Score += s.game.FacilityCaptureScore
+			}
+		}
+	}
+}
+
+func (s *Simulator) inside(f *Facility, x, y float64) bool {
+	return x >= f.Left && x <= f.Left+s.game.FacilityWidth && y >= f.Top && y <= f.Top+s.game.FacilityHeight
+}
+
+// produceVehicles advances production of owned factories and places the
+// finished vehicle at the first free spot inside the factory.
+func (s *Simulator) produceVehicles() {
+	for _, f := range s.facilities {
+		if f.FacilityType != Facility_VehicleFactory || f.OwnerPlayerId == -1 || f.VehicleType == Vehicle_None {
+			continue
+		}
+
+		if f.ProductionProgress < s.game.ProductionCost(f.VehicleType) {
+			f.ProductionProgress++
+			continue
+		}
+
+		if x, y, ok := s.freeSpot(f); ok {
+			s.addVehicle(f.OwnerPlayerId, f.VehicleType, x, y)
+			f.ProductionProgress = 0
+		}
+	}
+}
+
+func (s *Simulator) freeSpot(f *Facility) (float64, float64, bool) {
+	probe := newVehicle(s.game, 0, f.OwnerPlayerId, f.VehicleType, 0, 0)
+	r := probe.Radius
+
+	var nearby []*vehicle
+	for _, v := range s.vehicles {
+		if !v.dead && v.Aerial == probe.Aerial && (!probe.Aerial || v.PlayerId == probe.PlayerId) &&
+			v.X >= f.Left-2*r && v.X <= f.Left+s.game.FacilityWidth+2*r &&
+			v.Y >= f.Top-2*r && v.Y <= f.Top+s.game.FacilityHeight+2*r {
+			nearby = append(nearby, v)
+		}
+	}
+
+	for y := f.Top + r; y <= f.Top+s.game.FacilityHeight-r; y += armySpacing {
+		for x := f.Left + r; x <= f.Left+s.game.FacilityWidth-r; x += armySpacing {
+			free := true
+			for _, v := range nearby {
+				if v.GetSquaredDistanceTo(x, y) < 4*r*r {
+					free = false
+					break
+				}
+			}
+			if free {
+				return x, y, true
+			}
+		}
+	}
+
+	return 0, 0, false
+}
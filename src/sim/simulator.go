@@ -0,0 +1,287 @@
+// Package sim is a headless in-process implementation of the CodeWars game
+// rules. It drives two strategies tick by tick without the official runner
+// and is deterministic for a given Game.RandomSeed.
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	. "model"
+)
+
+// Strategy has the same method set as the main package Strategy, so any
+// strategy can be plugged into the simulator unchanged.
+type Strategy interface {
+	Move(*Player, *World, *Game, *Move)
+}
+
+// Result is the outcome of a simulated game. Winner is the index of the
+// winning strategy or -1 for a draw.
+type Result struct {
+	Ticks   int
+	Scores  [2]int
+	Crashed [2]bool
+	Winner  int
+}
+
+type vehicle struct {
+	Vehicle
+
+	order  order
+	repair float64
+	dead   bool
+}
+
+type player struct {
+	Player
+
+	index    int
+	strategy Strategy
+	actions  []int
+	view     *view
+}
+
+type Simulator struct {
+	game *Game
+	rng  *rand.Rand
+
+	tick    int
+	over    bool
+	players [2]*player
+
+	vehicles []*vehicle
+	byId     map[int64]*vehicle
+	nextId   int64
+
+	// facilities keep capture points relative to the first player.
+	facilities []*Facility
+
+	terrain    [][]Terrain
+	weather    [][]Weather
+	terrainMap *TerrainMap
+}
+
+// New creates a simulator for two strategies. The first strategy starts in
+// the top left corner of the map, the second one in the bottom right.
+func New(g *Game, first, second Strategy) *Simulator {
+	s := &Simulator{
+		game:   g,
+		rng:    rand.New(rand.NewSource(g.RandomSeed)),
+		byId:   make(map[int64]*vehicle),
+		nextId: 1,
+	}
+
+	for i, st := range [2]Strategy{first, second} {
+		p := &player{index: i, strategy: st}
+		p.Id = int64(i + 1)
+		p.NextNuclearStrikeVehicleId = -1
+		p.NextNuclearStrikeTickIndex = -1
+		p.view = newView(p.Id)
+		s.players[i] = p
+	}
+
+	s.generateMap()
+	s.generateFacilities()
+	s.generateArmies()
+
+	return s
+}
+
+func (s *Simulator) Game() *Game {
+	return s.game
+}
+
+func (s *Simulator) TickIndex() int {
+	return s.tick
+}
+
+// Run plays the game to the end.
+func (s *Simulator) Run() Result {
+	for s.Step() {
+	}
+	return s.Result()
+}
+
+// Stop ends the game once the current tick is over. Like Step it must be
+// called from the goroutine running the simulator, a strategy may call it
+// from its Move.
+func (s *Simulator) Stop() {
+	s.over = true
+}
+
+// Step asks both strategies for a move, applies the moves and advances the
+// world by one tick. It returns false once the game is over.
+func (s *Simulator) Step() bool {
+	if s.over {
+		return false
+	}
+
+	moves := [2]*Move{}
+	for i, p := range s.players {
+		moves[i] = s.requestMove(p)
+	}
+	for i, p := range s.players {
+		s.applyMove(p, moves[i])
+	}
+
+	s.detonateNuclearStrikes()
+	s.moveVehicles()
+	s.attack()
+	s.repair()
+	s.captureFacilities()
+	s.produceVehicles()
+	s.removeDeadVehicles()
+	s.updateCooldowns()
+
+	s.tick++
+	s.checkGameOver()
+
+	return !s.over
+}
+
+func (s *Simulator) Result() Result {
+	r := Result{Ticks: s.tick, Winner: -1}
+	for i, p := range s.players {
+		r.Scores[i] = p.Score
+		r.Crashed[i] = p.StrategyCrashed
+	}
+	switch {
+	case r.Scores[0] > r.Scores[1]:
+		r.Winner = 0
+	case r.Scores[1] > r.Scores[0]:
+		r.Winner = 1
+	}
+	return r
+}
+
+// Vehicles returns copies of all alive vehicles ordered by id.
+func (s *Simulator) Vehicles() []*Vehicle {
+	vehicles := make([]*Vehicle, 0, len(s.vehicles))
+	for _, v := range s.vehicles {
+		c := v.Vehicle
+		c.Groups = append([]int(nil), v.Groups...)
+		vehicles = append(vehicles, &c)
+	}
+	return vehicles
+}
+
+// Players returns copies of both players as seen by the first one.
+func (s *Simulator) Players() []*Player {
+	return s.playersFor(s.players[0])
+}
+
+func (s *Simulator) playersFor(viewer *player) []*Player {
+	players := make([]*Player, 0, len(s.players))
+	for _, p := range s.players {
+		c := p.Player
+		c.Me = p == viewer
+		players = append(players, &c)
+	}
+	return players
+}
+
+func (s *Simulator) requestMove(p *player) (m *Move) {
+	m = NewMove()
+
+	if p.StrategyCrashed {
+		return m
+	}
+
+	p.RemainingActionCooldownTicks = s.actionCooldown(p)
+	w := s.buildWorld(p)
+	me := p.Player
+	me.Me = true
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.StrategyCrashed = true
+			m.Action = Action_None
+		}
+	}()
+
+	p.strategy.Move(&me, w, s.game, m)
+
+	return m
+}
+
+func (s *Simulator) checkGameOver() {
+	alive := [2]int{}
+	for _, v := range s.vehicles {
+		alive[s.playerIndex(v.PlayerId)]++
+	}
+
+	// Armies wiped out on the same tick are a draw, only a sole survivor wins
+	// the victory bonus.
+	switch {
+	case alive[0] == 0 && alive[1] == 0:
+		s.over = true
+	case alive[0] == 0 || alive[1] == 0:
+		winner := 0
+		if alive[0] == 0 {
+			winner = 1
+		}
+		s.players[winner].Score += s.game.VictoryScore
+		s.over = true
+	}
+
+	if s.tick >= s.game.TickCount || (s.players[0].StrategyCrashed && s.players[1].StrategyCrashed) {
+		s.over = true
+	}
+}
+
+func (s *Simulator) playerIndex(id int64) int {
+	for i, p := range s.players {
+		if p.Id == id {
+			return i
+		}
+	}
+	panic(fmt.Sprintf("sim: unknown player %d", id))
+}
+
+func (s *Simulator) addVehicle(playerId int64, t VehicleType, x, y float64) *vehicle {
+	v := &vehicle{Vehicle: *newVehicle(s.game, s.nextId, playerId, t, x, y)}
+	s.nextId++
+	s.vehicles = append(s.vehicles, v)
+	s.byId[v.Id] = v
+	return v
+}
+
+func (s *Simulator) removeDeadVehicles() {
+	alive := s.vehicles[:0]
+	for _, v := range s.vehicles {
+		if v.dead {
+			delete(s.byId, v.Id)
+			continue
+		}
+		alive = append(alive, v)
+	}
+	for i := len(alive); i < len(s.vehicles); i++ {
+		s.vehicles[i] = nil
+	}
+	s.vehicles = alive
+}
+
+func (s *Simulator) updateCooldowns() {
+	for _, v := range s.vehicles {
+		if v.RemainingAttackCooldownTicks > 0 {
+			v.RemainingAttackCooldownTicks--
+		}
+	}
+	for _, p := range s.players {
+		if p.RemainingNuclearStrikeCooldownTicks > 0 {
+			p.RemainingNuclearStrikeCooldownTicks--
+		}
+	}
+}
+
+// playerVehicles returns the vehicles of the given player. Like s.vehicles
+// they are ordered by id.
+func (s *Simulator) playerVehicles(playerId int64) []*vehicle {
+	var vehicles []*vehicle
+	for _, v := range s.vehicles {
+		if v.PlayerId == playerId {
+			vehicles = append(vehicles, v)
+		}
+	}
+	return vehicles
+}
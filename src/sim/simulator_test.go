@@ -0,0 +1,295 @@
+package sim
+
+import (
+	"math"
+	. "model"
+	"reflect"
+	"testing"
+)
+
+type strategyFunc func(*Player, *World, *Game, *Move)
+
+func (f strategyFunc) Move(p *Player, w *World, g *Game, m *Move) {
+	f(p, w, g, m)
+}
+
+var idle = strategyFunc(func(*Player, *World, *Game, *Move) {})
+
+// rush selects the whole army and sends it to the centre of the map.
+var rush = strategyFunc(func(p *Player, w *World, g *Game, m *Move) {
+	switch w.TickIndex {
+	case 0:
+		m.Action = Action_ClearAndSelect
+		m.Right, m.Bottom = g.WorldWidth, g.WorldHeight
+	case 1:
+		mine := w.Vehicles.Mine()
+		var x, y float64
+		for _, v := range mine {
+			x += v.X / float64(len(mine))
+			y += v.Y / float64(len(mine))
+		}
+		m.Action = Action_Move
+		m.X, m.Y = g.WorldWidth/2-x, g.WorldHeight/2-y
+	}
+})
+
+// emptySimulator returns a simulator without vehicles and facilities, for
+// the tests to set up the scene of a single rule.
+func emptySimulator() *Simulator {
+	s := New(DefaultGame(1), idle, idle)
+	s.vehicles = nil
+	s.byId = make(map[int64]*vehicle)
+	s.facilities = nil
+	return s
+}
+
+func TestDeterminism(t *testing.T) {
+	play := func() (Result, []*Vehicle) {
+		g := DefaultGame(42)
+		g.TickCount = 600
+		s := New(g, rush, rush)
+		return s.Run(), s.Vehicles()
+	}
+
+	r1, v1 := play()
+	r2, v2 := play()
+	if r1 != r2 {
+		t.Errorf("results differ: %+v and %+v", r1, r2)
+	}
+	if !reflect.DeepEqual(v1, v2) {
+		t.Errorf("vehicles differ after %d ticks", r1.Ticks)
+	}
+	if r1.Scores == [2]int{} {
+		t.Errorf("armies never met in %d ticks", r1.Ticks)
+	}
+}
+
+func TestMovement(t *testing.T) {
+	s := emptySimulator()
+	p := s.players[0]
+	v := s.addVehicle(p.Id, Vehicle_Tank, 100, 100)
+
+	s.applyMove(p, &Move{Action: Action_ClearAndSelect, Right: 200, Bottom: 200, Type: Vehicle_None})
+	s.applyMove(p, &Move{Action: Action_Move, X: 10})
+
+	_, _, factor := s.factors(100, 100, false)
+	s.moveVehicles()
+	if want := 100 + v.MaxSpeed*factor; math.Abs(v.X-want) > 1e-9 || v.Y != 100 {
+		t.Errorf("after one tick at (%v, %v), want (%v, 100)", v.X, v.Y, want)
+	}
+
+	for i := 0; i < 100; i++ {
+		s.moveVehicles()
+	}
+	if v.X != 110 || v.Y != 100 || v.order.action != Action_None {
+		t.Errorf("at (%v, %v) with order %v, want (110, 100) and no order", v.X, v.Y, v.order.action)
+	}
+}
+
+func TestCollisions(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		t       VehicleType
+		blocked bool
+	}{
+		{"ground", Vehicle_Tank, true},
+		{"aerial of another player", Vehicle_Fighter, false},
+	} {
+		s := emptySimulator()
+		v := s.addVehicle(s.players[0].Id, c.t, 100, 100)
+		s.addVehicle(s.players[1].Id, c.t, 110, 100)
+		v.order = order{action: Action_Move, x: 120, y: 100}
+
+		for i := 0; i < 100; i++ {
+			s.moveVehicles()
+		}
+
+		if c.blocked && v.X > 110-2*v.Radius+1e-9 {
+			t.Errorf("%s: moved to %v through the vehicle at 110", c.name, v.X)
+		}
+		if !c.blocked && v.X != 120 {
+			t.Errorf("%s: stopped at %v, want 120", c.name, v.X)
+		}
+	}
+}
+
+func TestAttack(t *testing.T) {
+	s := emptySimulator()
+	me, enemy := s.players[0], s.players[1]
+	a := s.addVehicle(me.Id, Vehicle_Tank, 100, 100)
+	d := s.addVehicle(enemy.Id, Vehicle_Tank, 110, 100)
+	far := s.addVehicle(enemy.Id, Vehicle_Tank, 200, 100)
+
+	dmg := Damage(&a.Vehicle, &d.Vehicle)
+	d.Durability = dmg
+
+	s.attack()
+	s.removeDeadVehicles()
+
+	if s.byId[d.Id] != nil {
+		t.Errorf("target with %d durability survived %d damage", dmg, dmg)
+	}
+	if me.Score != s.game.VehicleEliminationScore {
+		t.Errorf("attacker scored %d, want %d", me.Score, s.game.VehicleEliminationScore)
+	}
+	if a.RemainingAttackCooldownTicks != a.AttackCooldownTicks {
+		t.Errorf("attacker cooldown %d, want %d", a.RemainingAttackCooldownTicks, a.AttackCooldownTicks)
+	}
+	if want := a.MaxDurability - Damage(&d.Vehicle, &a.Vehicle); a.Durability != want {
+		t.Errorf("attacker durability %d, want %d: fire is simultaneous", a.Durability, want)
+	}
+	if far.Durability != far.MaxDurability {
+		t.Errorf("vehicle out of range took %d damage", far.MaxDurability-far.Durability)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	s := emptySimulator()
+	me, enemy := s.players[0], s.players[1]
+	near := s.addVehicle(me.Id, Vehicle_Tank, 100, 100)
+	s.addVehicle(me.Id, Vehicle_Arrv, 105, 100)
+	beside := s.addVehicle(me.Id, Vehicle_Tank, 300, 100)
+	s.addVehicle(enemy.Id, Vehicle_Arrv, 305, 100)
+	near.Durability, beside.Durability = 50, 50
+
+	for i := 0; i < 15; i++ {
+		s.repair()
+	}
+
+	if near.Durability != 51 {
+		t.Errorf("durability next to a friendly ARRV %d, want 51", near.Durability)
+	}
+	if beside.Durability != 50 {
+		t.Errorf("durability next to an enemy ARRV %d, want 50", beside.Durability)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	s := emptySimulator()
+	me, enemy := s.players[0], s.players[1]
+	max := s.game.MaxFacilityCapturePoints
+	f := &Facility{Id: 1, FacilityType: Facility_ControlCenter, OwnerPlayerId: -1, Left: 64, Top: 64, VehicleType: Vehicle_None}
+	s.facilities = []*Facility{f}
+
+	tank := s.addVehicle(me.Id, Vehicle_Tank, 90, 90)
+	s.addVehicle(me.Id, Vehicle_Fighter, 100, 100)
+	f.CapturePoints = max - s.game.FacilityCapturePointsPerVehiclePerTick/2
+
+	s.captureFacilities()
+	if f.OwnerPlayerId != me.Id || f.CapturePoints != max {
+		t.Fatalf("owner %d with %v points, want %d with %v", f.OwnerPlayerId, f.CapturePoints, me.Id, max)
+	}
+	if me.Score != s.game.FacilityCaptureScore {
+		t.Errorf("score %d, want %d", me.Score, s.game.FacilityCaptureScore)
+	}
+
+	// A contested facility doesn't change. Once the enemy holds it alone and
+	// pushes the points past zero it loses its owner.
+	s.addVehicle(enemy.Id, Vehicle_Tank, 100, 100)
+	s.captureFacilities()
+	if f.CapturePoints != max {
+		t.Errorf("contested facility went to %v points", f.CapturePoints)
+	}
+
+	tank.dead = true
+	s.removeDeadVehicles()
+	f.CapturePoints = s.game.FacilityCapturePointsPerVehiclePerTick / 2
+	s.captureFacilities()
+	if f.OwnerPlayerId != -1 || f.CapturePoints >= 0 {
+		t.Errorf("owner %d with %v points, want -1 with negative points", f.OwnerPlayerId, f.CapturePoints)
+	}
+}
+
+func TestProduction(t *testing.T) {
+	s := emptySimulator()
+	me := s.players[0]
+	f := &Facility{Id: 1, FacilityType: Facility_VehicleFactory, OwnerPlayerId: me.Id, Left: 64, Top: 64, VehicleType: Vehicle_None}
+	s.facilities = []*Facility{f}
+
+	s.applyMove(me, &Move{Action: Action_SetupVehicleProduction, FacilityId: f.Id, Type: Vehicle_Ifv})
+	if f.VehicleType != Vehicle_Ifv {
+		t.Fatalf("factory produces %v, want %v", f.VehicleType, Vehicle_Ifv)
+	}
+
+	cost := s.game.ProductionCost(Vehicle_Ifv)
+	for i := 0; i < cost; i++ {
+		s.produceVehicles()
+	}
+	if len(s.vehicles) != 0 || f.ProductionProgress != cost {
+		t.Fatalf("%d vehicles with progress %d after %d ticks, want none with %d", len(s.vehicles), f.ProductionProgress, cost, cost)
+	}
+
+	s.produceVehicles()
+	if len(s.vehicles) != 1 || f.ProductionProgress != 0 {
+		t.Fatalf("%d vehicles with progress %d, want 1 with 0", len(s.vehicles), f.ProductionProgress)
+	}
+	if v := s.vehicles[0]; v.Type != Vehicle_Ifv || v.PlayerId != me.Id || !s.inside(f, v.X, v.Y) {
+		t.Errorf("produced %v of player %d at (%v, %v)", v.Type, v.PlayerId, v.X, v.Y)
+	}
+}
+
+func TestNuclearStrike(t *testing.T) {
+	for _, cancelled := range []bool{false, true} {
+		s := emptySimulator()
+		me, enemy := s.players[0], s.players[1]
+		spotter := s.addVehicle(me.Id, Vehicle_Fighter, 100, 100)
+		centre := s.addVehicle(enemy.Id, Vehicle_Tank, 150, 100)
+		edge := s.addVehicle(enemy.Id, Vehicle_Tank, 175, 100)
+		outside := s.addVehicle(enemy.Id, Vehicle_Tank, 210, 100)
+
+		s.applyMove(me, &Move{Action: Action_TacticalNuclearStrike, VehicleId: spotter.Id, X: 150, Y: 100})
+		if me.NextNuclearStrikeTickIndex != s.game.TacticalNuclearStrikeDelay ||
+			me.RemainingNuclearStrikeCooldownTicks != s.game.BaseTacticalNuclearStrikeCooldown {
+			t.Fatalf("strike at tick %d with cooldown %d", me.NextNuclearStrikeTickIndex, me.RemainingNuclearStrikeCooldownTicks)
+		}
+
+		if cancelled {
+			spotter.dead = true
+		}
+		for s.tick = 0; s.tick < s.game.TacticalNuclearStrikeDelay; s.tick++ {
+			s.detonateNuclearStrikes()
+			if centre.Durability != centre.MaxDurability && !cancelled {
+				t.Fatalf("strike landed early at tick %d", s.tick)
+			}
+		}
+		s.detonateNuclearStrikes()
+
+		want := [3]int{100, 100, 100}
+		if !cancelled {
+			want = [3]int{100 - 99, 100 - 49, 100}
+		}
+		if got := [3]int{centre.Durability, edge.Durability, outside.Durability}; got != want {
+			t.Errorf("cancelled %v: durabilities %v, want %v", cancelled, got, want)
+		}
+		if me.NextNuclearStrikeTickIndex != -1 || me.NextNuclearStrikeVehicleId != -1 {
+			t.Errorf("cancelled %v: strike still pending", cancelled)
+		}
+	}
+}
+
+func TestGameOver(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		alive  [2]bool
+		scores [2]int
+		over   bool
+	}{
+		{"both alive", [2]bool{true, true}, [2]int{0, 0}, false},
+		{"first wiped out", [2]bool{false, true}, [2]int{0, 1000}, true},
+		{"second wiped out", [2]bool{true, false}, [2]int{1000, 0}, true},
+		{"mutual wipe-out", [2]bool{false, false}, [2]int{0, 0}, true},
+	} {
+		s := emptySimulator()
+		for i, alive := range c.alive {
+			if alive {
+				s.addVehicle(s.players[i].Id, Vehicle_Tank, 100, 100)
+			}
+		}
+
+		s.checkGameOver()
+		r := s.Result()
+		if s.over != c.over || r.Scores != c.scores {
+			t.Errorf("%s: over %v with scores %v, want %v with %v", c.name, s.over, r.Scores, c.over, c.scores)
+		}
+	}
+}
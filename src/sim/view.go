@@ -0,0 +1,188 @@
+package sim
+
+import (
+	. "model"
+	"sort"
+)
+
+// snapshot is the state of a vehicle last reported to a player, used to send
+// only the vehicles that changed since.
+type snapshot struct {
+	tick       int
+	x, y       float64
+	durability int
+	cooldown   int
+	selected   bool
+	groups     []int
+}
+
+// view is what a single player knows about the world. Like the official
+// runner it reuses one World across ticks and reports vehicles as deltas.
+type view struct {
+	playerId int64
+	world    *World
+	known    map[int64]*snapshot
+}
+
+func newView(playerId int64) *view {
+	return &view{
+		playerId: playerId,
+		world:    new(World),
+		known:    make(map[int64]*snapshot),
+	}
+}
+
+func (s *Simulator) buildWorld(p *player) *World {
+	v := p.view
+	w := v.world
+
+	w.TickIndex = s.tick
+	w.TickCount = s.game.TickCount
+	w.Width = s.game.WorldWidth
+	w.Height = s.game.WorldHeight
+	w.Players = s.playersFor(p)
+	w.NewVehicles = nil
+	w.VehicleUpdates = nil
+
+	visible := s.visibility(p)
+
+	for _, veh := range s.vehicles {
+		if !visible(veh) {
+			continue
+		}
+
+		selected, groups := veh.Selected, veh.Groups
+		if veh.PlayerId != p.Id {
+			selected, groups = false, nil
+		}
+
+		snap, ok := v.known[veh.Id]
+		if ok {
+			snap.tick = s.tick
+			if !snap.changed(&veh.Vehicle, selected, groups) {
+				continue
+			}
+		}
+
+		snap = &snapshot{
+			tick:       s.tick,
+			x:          veh.X,
+			y:          veh.Y,
+			durability: veh.Durability,
+			cooldown:   veh.RemainingAttackCooldownTicks,
+			selected:   selected,
+			groups:     append([]int(nil), groups...),
+		}
+
+		if !ok {
+			c := veh.Vehicle
+			c.Selected = snap.selected
+			c.Groups = snap.groups
+			w.NewVehicles = append(w.NewVehicles, &c)
+		} else {
+			w.VehicleUpdates = append(w.VehicleUpdates, &VehicleUpdate{
+				Id:                           veh.Id,
+				X:                            snap.x,
+				Y:                            snap.y,
+				Durability:                   snap.durability,
+				RemainingAttackCooldownTicks: snap.cooldown,
+				Selected:                     snap.selected,
+				Groups:                       snap.groups,
+			})
+		}
+		v.known[veh.Id] = snap
+	}
+
+	var gone []int64
+	for id, snap := range v.known {
+		if snap.tick != s.tick {
+			gone = append(gone, id)
+		}
+	}
+	sort.Slice(gone, func(i, j int) bool { return gone[i] < gone[j] })
+
+	for _, id := range gone {
+		snap := v.known[id]
+		w.VehicleUpdates = append(w.VehicleUpdates, &VehicleUpdate{Id: id, X: snap.x, Y: snap.y})
+		delete(v.known, id)
+	}
+
+	if s.tick == 0 {
+		w.TerrainByCellXY = make([][]Terrain, len(s.terrain))
+		w.WeatherByCellXY = make([][]Weather, len(s.weather))
+		for x := range s.terrain {
+			w.TerrainByCellXY[x] = append([]Terrain(nil), s.terrain[x]...)
+			w.WeatherByCellXY[x] = append([]Weather(nil), s.weather[x]...)
+		}
+	}
+
+	w.Facilities = make([]*Facility, 0, len(s.facilities))
+	for _, f := range s.facilities {
+		c := *f
+		if p.index == 1 {
+			c.CapturePoints = -c.CapturePoints
+		}
+		w.Facilities = append(w.Facilities, &c)
+	}
+
+	if w.Vehicles == nil {
+		w.Vehicles = NewVehicleRegistry()
+	}
+	w.Vehicles.Update(w)
+
+	if w.Map == nil {
+		w.Map = NewTerrainMap(s.game)
+	}
+	w.Map.Update(w)
+
+	return w
+}
+
+func (s *snapshot) changed(v *Vehicle, selected bool, groups []int) bool {
+	if s.x != v.X || s.y != v.Y || s.durability != v.Durability ||
+		s.cooldown != v.RemainingAttackCooldownTicks || s.selected != selected ||
+		len(s.groups) != len(groups) {
+		return true
+	}
+	for i, g := range s.groups {
+		if groups[i] != g {
+			return true
+		}
+	}
+	return false
+}
+
+// visibility returns a predicate telling whether the player can see the
+// vehicle. Without fog of war every vehicle is visible. Otherwise an enemy is
+// visible if it is within the vision range of one of the player's vehicles,
+// scaled by the observer's vision factor and the target's stealth factor.
+func (s *Simulator) visibility(p *player) func(*vehicle) bool {
+	if !s.game.FogOfWarEnabled {
+		return func(*vehicle) bool { return true }
+	}
+
+	own := s.playerVehicles(p.Id)
+	ranges := make(map[int64]float64, len(own))
+	maxRange := 0.0
+	for _, o := range own {
+		vision, _, _ := s.factors(o.X, o.Y, o.Aerial)
+		ranges[o.Id] = o.VisionRange * vision
+		if ranges[o.Id] > maxRange {
+			maxRange = ranges[o.Id]
+		}
+	}
+	index := s.index(own)
+
+	return func(v *vehicle) bool {
+		if v.PlayerId == p.Id {
+			return true
+		}
+
+		_, stealth, _ := s.factors(v.X, v.Y, v.Aerial)
+		observers := index.Radius(v.X, v.Y, maxRange*stealth, func(o *Vehicle) bool {
+			r := ranges[o.Id] * stealth
+			return o.GetSquaredDistanceUnit(&v.Unit) <= r*r
+		})
+		return len(observers) > 0
+	}
+}
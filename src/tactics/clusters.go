@@ -0,0 +1,296 @@
+package tactics
+
+import (
+	"math"
+	. "model"
+	"sort"
+)
+
+// Cluster is a group of enemy vehicles standing close to each other.
+type Cluster struct {
+	// Id stays the same as long as the cluster keeps most of its surviving
+	// vehicles.
+	Id int
+
+	// Vehicles are the members ordered by id.
+	Vehicles []*Vehicle
+
+	CenterX, CenterY float64
+
+	// Radius is the distance from the centre to the farthest member.
+	Radius float64
+
+	// Composition counts the members of every type.
+	Composition map[VehicleType]int
+
+	Durability    int
+	MaxDurability int
+
+	// VX and VY is the mean velocity per tick of the members.
+	VX, VY float64
+
+	// FirstTick is the tick the cluster was first seen on.
+	FirstTick int
+}
+
+// Speed returns the length of the velocity of the cluster.
+func (c *Cluster) Speed() float64 {
+	return math.Hypot(c.VX, c.VY)
+}
+
+// Predict returns the position of the centre after the given number of ticks
+// if the cluster keeps its velocity.
+func (c *Cluster) Predict(ticks int) (x, y float64) {
+	return c.CenterX + c.VX*float64(ticks), c.CenterY + c.VY*float64(ticks)
+}
+
+// EnemyClusters splits the visible enemy vehicles into clusters with DBSCAN
+// and tracks them from tick to tick. Call Update once per tick after
+// World.Vehicles is updated.
+type EnemyClusters struct {
+	// Eps is the largest distance between neighbouring vehicles of a
+	// cluster, MinPoints the number of vehicles within Eps, counting the
+	// vehicle itself, that makes a vehicle the core of a cluster. Vehicles
+	// that are neither a core nor next to one are noise.
+	Eps       float64
+	MinPoints int
+
+	index    *SpatialIndex
+	clusters []*Cluster
+	noise    []*Vehicle
+	of       map[int64]*Cluster
+	last     map[int64]position
+	nextId   int
+}
+
+// position is where a vehicle was seen last.
+type position struct {
+	x, y float64
+	tick int
+}
+
+// NewEnemyClusters sets Eps so that vehicles in the usual formations, a few
+// radii apart, fall into the same cluster.
+func NewEnemyClusters(g *Game) *EnemyClusters {
+	eps := g.VehicleRadius * 5
+	return &EnemyClusters{
+		Eps:       eps,
+		MinPoints: 3,
+		index:     NewSpatialIndex(g.WorldWidth, g.WorldHeight, eps),
+		of:        make(map[int64]*Cluster),
+		last:      make(map[int64]position),
+		nextId:    1,
+	}
+}
+
+func (c *EnemyClusters) Update(w *World) {
+	enemies := w.Vehicles.Enemy()
+	c.index.Build(enemies)
+
+	velocity := make(map[int64][2]float64, len(enemies))
+	last := make(map[int64]position, len(enemies))
+	for _, v := range enemies {
+		if p, ok := c.last[v.Id]; ok && p.tick < w.TickIndex {
+			dt := float64(w.TickIndex - p.tick)
+			velocity[v.Id] = [2]float64{(v.X - p.x) / dt, (v.Y - p.y) / dt}
+		}
+		last[v.Id] = position{v.X, v.Y, w.TickIndex}
+	}
+	c.last = last
+
+	members, noise := c.dbscan(enemies)
+
+	clusters := make([]*Cluster, 0, len(members))
+	for _, vs := range members {
+		clusters = append(clusters, summarize(vs, velocity))
+	}
+	c.identify(clusters, w)
+
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Id < clusters[j].Id })
+	c.clusters = clusters
+	c.noise = noise
+
+	c.of = make(map[int64]*Cluster, len(enemies))
+	for _, cl := range clusters {
+		for _, v := range cl.Vehicles {
+			c.of[v.Id] = cl
+		}
+	}
+}
+
+// dbscan returns the members of every cluster and the noise, each ordered by
+// id.
+func (c *EnemyClusters) dbscan(vehicles []*Vehicle) (clusters [][]*Vehicle, noise []*Vehicle) {
+	const unvisited, noisy = 0, -1
+	label := make(map[int64]int, len(vehicles))
+
+	for _, v := range vehicles {
+		if label[v.Id] != unvisited {
+			continue
+		}
+
+		neighbours := c.index.Radius(v.X, v.Y, c.Eps, nil)
+		if len(neighbours) < c.MinPoints {
+			label[v.Id] = noisy
+			continue
+		}
+
+		id := len(clusters) + 1
+		label[v.Id] = id
+		cluster := []*Vehicle{v}
+
+		for queue := neighbours; len(queue) > 0; {
+			n := queue[0]
+			queue = queue[1:]
+
+			switch label[n.Id] {
+			case noisy:
+				// A border vehicle: it joins but doesn't expand the cluster.
+				label[n.Id] = id
+				cluster = append(cluster, n)
+				continue
+			case unvisited:
+				label[n.Id] = id
+				cluster = append(cluster, n)
+			default:
+				continue
+			}
+
+			if next := c.index.Radius(n.X, n.Y, c.Eps, nil); len(next) >= c.MinPoints {
+				queue = append(queue, next...)
+			}
+		}
+
+		sort.Slice(cluster, func(i, j int) bool { return cluster[i].Id < cluster[j].Id })
+		clusters = append(clusters, cluster)
+	}
+
+	for _, v := range vehicles {
+		if label[v.Id] == noisy {
+			noise = append(noise, v)
+		}
+	}
+	return clusters, noise
+}
+
+func summarize(vehicles []*Vehicle, velocity map[int64][2]float64) *Cluster {
+	cl := &Cluster{
+		Vehicles:    vehicles,
+		Composition: make(map[VehicleType]int),
+	}
+
+	for _, v := range vehicles {
+		cl.CenterX += v.X
+		cl.CenterY += v.Y
+		cl.Composition[v.Type]++
+		cl.Durability += v.Durability
+		cl.MaxDurability += v.MaxDurability
+		vel := velocity[v.Id]
+		cl.VX += vel[0]
+		cl.VY += vel[1]
+	}
+
+	n := float64(len(vehicles))
+	cl.CenterX /= n
+	cl.CenterY /= n
+	cl.VX /= n
+	cl.VY /= n
+
+	for _, v := range vehicles {
+		cl.Radius = math.Max(cl.Radius, v.GetDistanceTo(cl.CenterX, cl.CenterY))
+	}
+	return cl
+}
+
+// identify gives the clusters the ids of the clusters of the previous tick
+// they share the most vehicles with, provided they share more than half of
+// the old members still alive, so losses alone don't change the id. Every old
+// id goes to at most one new cluster, the one sharing the most; the others
+// get new ids.
+func (c *EnemyClusters) identify(clusters []*Cluster, w *World) {
+	type match struct {
+		cluster *Cluster
+		old     *Cluster
+		shared  int
+	}
+
+	surviving := make(map[*Cluster]int)
+	for _, old := range c.clusters {
+		for _, v := range old.Vehicles {
+			if w.Vehicles.Get(v.Id) != nil {
+				surviving[old]++
+			}
+		}
+	}
+
+	var matches []match
+	for _, cl := range clusters {
+		shared := make(map[*Cluster]int)
+		for _, v := range cl.Vehicles {
+			if old := c.of[v.Id]; old != nil {
+				shared[old]++
+			}
+		}
+		for old, n := range shared {
+			if n*2 > surviving[old] {
+				matches = append(matches, match{cl, old, n})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].shared != matches[j].shared {
+			return matches[i].shared > matches[j].shared
+		}
+		if matches[i].old.Id != matches[j].old.Id {
+			return matches[i].old.Id < matches[j].old.Id
+		}
+		return matches[i].cluster.Vehicles[0].Id < matches[j].cluster.Vehicles[0].Id
+	})
+
+	taken := make(map[int]bool)
+	for _, m := range matches {
+		if m.cluster.Id != 0 || taken[m.old.Id] {
+			continue
+		}
+		m.cluster.Id = m.old.Id
+		m.cluster.FirstTick = m.old.FirstTick
+		taken[m.old.Id] = true
+	}
+
+	for _, cl := range clusters {
+		if cl.Id == 0 {
+			cl.Id = c.nextId
+			cl.FirstTick = w.TickIndex
+			c.nextId++
+		}
+	}
+}
+
+// Get returns the cluster with the id or nil if it is gone.
+func (c *EnemyClusters) Get(id int) *Cluster {
+	for _, cl := range c.clusters {
+		if cl.Id == id {
+			return cl
+		}
+	}
+	return nil
+}
+
+// Of returns the cluster of the vehicle or nil if it is noise or unknown.
+func (c *EnemyClusters) Of(vehicleId int64) *Cluster {
+	return c.of[vehicleId]
+}
+
+// All returns the clusters ordered by id.
+func (c *EnemyClusters) All() []*Cluster {
+	return c.clusters
+}
+
+func (c *EnemyClusters) Len() int {
+	return len(c.clusters)
+}
+
+// Noise returns the enemy vehicles that belong to no cluster, ordered by id.
+func (c *EnemyClusters) Noise() []*Vehicle {
+	return c.noise
+}
@@ -0,0 +1,113 @@
+package tactics
+
+import (
+	. "model"
+	"testing"
+)
+
+// clusterWorld returns a world with enemy tanks at the given positions, the
+// ids counting from 1.
+func clusterWorld(g *Game, tick int, registry *VehicleRegistry, positions [][2]float64) *World {
+	w := &World{
+		TickIndex: tick,
+		Players:   []*Player{{Id: 1, Me: true}, {Id: 2}},
+		Vehicles:  registry,
+	}
+	alive := make(map[int64]bool)
+	for i, p := range positions {
+		id := int64(i + 1)
+		alive[id] = true
+		if registry.Get(id) == nil {
+			v := g.NewVehicle(Vehicle_Tank)
+			v.Id, v.PlayerId, v.X, v.Y = id, 2, p[0], p[1]
+			w.NewVehicles = append(w.NewVehicles, v)
+			continue
+		}
+		w.VehicleUpdates = append(w.VehicleUpdates, &VehicleUpdate{Id: id, X: p[0], Y: p[1], Durability: 100})
+	}
+	for _, v := range registry.All() {
+		if !alive[v.Id] {
+			w.VehicleUpdates = append(w.VehicleUpdates, &VehicleUpdate{Id: v.Id})
+		}
+	}
+	registry.Update(w)
+	return w
+}
+
+// row returns n positions 4 apart starting at (x, y), spaced more densely
+// than the default Eps.
+func row(x, y float64, n int) [][2]float64 {
+	var positions [][2]float64
+	for i := 0; i < n; i++ {
+		positions = append(positions, [2]float64{x + 4*float64(i), y})
+	}
+	return positions
+}
+
+func TestEnemyClustersIds(t *testing.T) {
+	g := DefaultGame(1)
+	registry := NewVehicleRegistry()
+	c := NewEnemyClusters(g)
+
+	// Ten vehicles in a row form one cluster.
+	c.Update(clusterWorld(g, 0, registry, row(100, 100, 10)))
+	if c.Len() != 1 {
+		t.Fatalf("tick 0: %d clusters, want 1", c.Len())
+	}
+	first := c.All()[0].Id
+
+	// Split into 6 and 4: the larger part keeps the id.
+	split := append(row(100, 100, 6), row(300, 300, 4)...)
+	c.Update(clusterWorld(g, 1, registry, split))
+	if c.Len() != 2 {
+		t.Fatalf("tick 1: %d clusters, want 2", c.Len())
+	}
+	if id := c.Of(1).Id; id != first {
+		t.Errorf("tick 1: the part with 6 of 10 vehicles has id %d, want %d", id, first)
+	}
+	if id := c.Of(7).Id; id == first {
+		t.Errorf("tick 1: the part with 4 of 10 vehicles kept id %d", id)
+	}
+	second := c.Of(7).Id
+
+	// Three of the six move away: half of the cluster isn't most of it,
+	// neither part keeps the id.
+	moved := append(row(100, 100, 3), row(500, 100, 3)...)
+	moved = append(moved, row(300, 300, 4)...)
+	c.Update(clusterWorld(g, 2, registry, moved))
+	if c.Len() != 3 {
+		t.Fatalf("tick 2: %d clusters, want 3", c.Len())
+	}
+	if c.Of(1).Id == first || c.Of(4).Id == first {
+		t.Errorf("tick 2: a part with 3 of 6 vehicles kept id %d", first)
+	}
+	if id := c.Of(7).Id; id != second {
+		t.Errorf("tick 2: the untouched cluster has id %d, want %d", id, second)
+	}
+}
+
+func TestEnemyClustersIdsAfterLosses(t *testing.T) {
+	g := DefaultGame(1)
+	registry := NewVehicleRegistry()
+	c := NewEnemyClusters(g)
+
+	c.Update(clusterWorld(g, 0, registry, row(100, 100, 10)))
+	first := c.All()[0].Id
+
+	// Six of the ten vehicles are destroyed: the four left are all the
+	// cluster has, it keeps its id.
+	c.Update(clusterWorld(g, 1, registry, row(100, 100, 4)))
+	if c.Len() != 1 || c.Of(1).Id != first {
+		t.Errorf("tick 1: %d clusters, the survivors have id %d, want 1 cluster with id %d", c.Len(), c.Of(1).Id, first)
+	}
+
+	// One survivor leaves to join two newcomers far away: the three staying
+	// are most of the survivors and keep the id.
+	c.Update(clusterWorld(g, 2, registry, append(row(100, 100, 3), row(500, 500, 3)...)))
+	if id := c.Of(1).Id; id != first {
+		t.Errorf("tick 2: the three survivors have id %d, want %d", id, first)
+	}
+	if id := c.Of(4).Id; id == first {
+		t.Errorf("tick 2: the survivor with the newcomers took id %d", id)
+	}
+}
@@ -0,0 +1,172 @@
+package tactics
+
+import (
+	"math"
+	. "model"
+	"sort"
+)
+
+// CaptureEstimate tells how the capture of a facility is going for us.
+type CaptureEstimate struct {
+	Facility *Facility
+
+	// Own and Enemy are the numbers of ground vehicles of each side inside
+	// the facility.
+	Own, Enemy int
+
+	// Ticks is the number of ticks until we own the facility if nobody
+	// enters or leaves it: 0 if we own it already, +Inf if the capture
+	// doesn't progress.
+	Ticks float64
+}
+
+// ProductionPlan is the production recommended for one of our factories.
+type ProductionPlan struct {
+	Facility *Facility
+
+	// Recommended is the type the factory should build, Switch is set if
+	// that means changing its production, which loses the progress made.
+	Recommended VehicleType
+	Switch      bool
+
+	// Ticks is the number of ticks until the factory produces its next
+	// vehicle of the current type, -1 if it produces nothing.
+	Ticks int
+}
+
+// Move fills m with the action setting up the recommended production.
+func (p *ProductionPlan) Move(m *Move) {
+	m.Action = Action_SetupVehicleProduction
+	m.FacilityId = p.Facility.Id
+	m.Type = p.Recommended
+}
+
+// FacilityPlanner estimates the capture of facilities and chooses what our
+// factories build.
+type FacilityPlanner struct {
+	game *Game
+}
+
+func NewFacilityPlanner(g *Game) *FacilityPlanner {
+	return &FacilityPlanner{game: g}
+}
+
+// Inside reports whether the vehicle stands inside the facility.
+func (p *FacilityPlanner) Inside(f *Facility, v *Vehicle) bool {
+	return v.X >= f.Left && v.X <= f.Left+p.game.FacilityWidth &&
+		v.Y >= f.Top && v.Y <= f.Top+p.game.FacilityHeight
+}
+
+// Capture estimates the capture of every facility, the fastest first.
+// Capture points move by FacilityCapturePointsPerVehiclePerTick per ground
+// vehicle towards the only side present; a facility held by both sides
+// doesn't change.
+func (p *FacilityPlanner) Capture(w *World) []*CaptureEstimate {
+	me := w.MyPlayer()
+	if me == nil {
+		return nil
+	}
+
+	vehicles := w.Vehicles.All()
+
+	estimates := make([]*CaptureEstimate, 0, len(w.Facilities))
+	for _, f := range w.Facilities {
+		e := &CaptureEstimate{Facility: f}
+		for _, v := range vehicles {
+			if v.Aerial || !p.Inside(f, v) {
+				continue
+			}
+			if v.PlayerId == me.Id {
+				e.Own++
+			} else {
+				e.Enemy++
+			}
+		}
+
+		switch {
+		case f.OwnerPlayerId == me.Id:
+			e.Ticks = 0
+		case e.Own == 0 || e.Enemy > 0:
+			e.Ticks = math.Inf(1)
+		default:
+			rate := float64(e.Own) * p.game.FacilityCapturePointsPerVehiclePerTick
+			e.Ticks = math.Ceil((p.game.MaxFacilityCapturePoints - f.CapturePoints) / rate)
+		}
+
+		estimates = append(estimates, e)
+	}
+
+	sort.SliceStable(estimates, func(i, j int) bool { return estimates[i].Ticks < estimates[j].Ticks })
+	return estimates
+}
+
+// ProductionTicks returns the number of ticks until the factory produces its
+// next vehicle, -1 if it produces nothing.
+func (p *FacilityPlanner) ProductionTicks(f *Facility) int {
+	if f.FacilityType != Facility_VehicleFactory || f.VehicleType == Vehicle_None {
+		return -1
+	}
+	if t := p.game.ProductionCost(f.VehicleType) - f.ProductionProgress; t > 0 {
+		return t
+	}
+	return 0
+}
+
+// Production returns a plan for every factory we own, based on the visible
+// enemy vehicles.
+func (p *FacilityPlanner) Production(w *World) []*ProductionPlan {
+	me := w.MyPlayer()
+	if me == nil {
+		return nil
+	}
+
+	recommended := p.Recommend(w.Vehicles.Enemy())
+
+	var plans []*ProductionPlan
+	for _, f := range w.Facilities {
+		if f.FacilityType != Facility_VehicleFactory || f.OwnerPlayerId != me.Id {
+			continue
+		}
+		plans = append(plans, &ProductionPlan{
+			Facility:    f,
+			Recommended: recommended,
+			Switch:      f.VehicleType != recommended,
+			Ticks:       p.ProductionTicks(f),
+		})
+	}
+	return plans
+}
+
+// Recommend returns the vehicle type that does best against the given enemy
+// army per tick of production: the damage it deals to the army times the
+// time it survives under the army's fire, divided by its production cost.
+// Without enemies every type counts as equally likely.
+func (p *FacilityPlanner) Recommend(enemies []*Vehicle) VehicleType {
+	count := make(map[VehicleType]int)
+	for _, e := range enemies {
+		count[e.Type]++
+	}
+	if len(count) == 0 {
+		for _, t := range vehicleTypes {
+			count[t] = 1
+		}
+	}
+
+	best, bestScore := Vehicle_Tank, -1.0
+	for _, t := range vehicleTypes {
+		v := p.game.NewVehicle(t)
+
+		dealt, taken := 0.0, 0.0
+		for _, e := range vehicleTypes {
+			n := count[e]
+			dealt += float64(n) * TypeDamagePerTick(p.game, t, e)
+			taken += float64(n) * TypeDamagePerTick(p.game, e, t)
+		}
+
+		score := dealt * float64(v.MaxDurability) / math.Max(taken, 1e-3) / float64(p.game.ProductionCost(t))
+		if score > bestScore {
+			best, bestScore = t, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,168 @@
+package tactics
+
+import (
+	"math"
+	. "model"
+	"testing"
+)
+
+// facilityWorld returns a world of player 1 with the facilities and the
+// vehicles.
+func facilityWorld(facilities []*Facility, vehicles ...*Vehicle) *World {
+	w := &World{
+		Players:     []*Player{{Id: 1, Me: true}, {Id: 2}},
+		Facilities:  facilities,
+		NewVehicles: vehicles,
+		Vehicles:    NewVehicleRegistry(),
+	}
+	w.Vehicles.Update(w)
+	return w
+}
+
+func TestFacilityCapture(t *testing.T) {
+	g := DefaultGame(1)
+	p := NewFacilityPlanner(g)
+
+	var vehicles []*Vehicle
+	add := func(vt VehicleType, player int64, x, y float64) {
+		v := g.NewVehicle(vt)
+		v.Id, v.PlayerId, v.X, v.Y = int64(len(vehicles)+1), player, x, y
+		vehicles = append(vehicles, v)
+	}
+
+	facilities := []*Facility{
+		{Id: 1, OwnerPlayerId: -1, Left: 0, Top: 0},
+		{Id: 2, OwnerPlayerId: 1, Left: 100, Top: 0},
+		{Id: 3, OwnerPlayerId: -1, Left: 200, Top: 0},
+		{Id: 4, OwnerPlayerId: 2, Left: 300, Top: 0, CapturePoints: -100},
+		{Id: 5, OwnerPlayerId: -1, Left: 400, Top: 0, CapturePoints: 50},
+		{Id: 6, OwnerPlayerId: -1, Left: 500, Top: 0},
+	}
+	// Two tanks capture the first facility, one of them on its edge.
+	add(Vehicle_Tank, 1, 10, 10)
+	add(Vehicle_Tank, 1, 64, 64)
+	// Both sides hold the third one.
+	add(Vehicle_Tank, 1, 210, 10)
+	add(Vehicle_Ifv, 2, 220, 10)
+	// One IFV takes back the fourth one from the enemy, four the fifth one.
+	add(Vehicle_Ifv, 1, 310, 10)
+	for i := 0; i < 4; i++ {
+		add(Vehicle_Ifv, 1, float64(410+10*i), 10)
+	}
+	// Aircraft don't capture.
+	add(Vehicle_Fighter, 1, 510, 10)
+	// Nor do vehicles outside the facility.
+	add(Vehicle_Tank, 1, 565, 10)
+
+	estimates := p.Capture(facilityWorld(facilities, vehicles...))
+
+	want := []struct {
+		id    int64
+		own   int
+		enemy int
+		ticks float64
+		never bool
+	}{
+		{2, 0, 0, 0, false},
+		{5, 4, 0, 2500, false},
+		{1, 2, 0, 10000, false},
+		{4, 1, 0, 40000, false},
+		{3, 1, 1, 0, true},
+		{6, 0, 0, 0, true},
+	}
+	if len(estimates) != len(want) {
+		t.Fatalf("%d estimates, want %d", len(estimates), len(want))
+	}
+	for i, w := range want {
+		e := estimates[i]
+		ticks := w.ticks
+		if w.never {
+			ticks = math.Inf(1)
+		}
+		if e.Facility.Id != w.id || e.Own != w.own || e.Enemy != w.enemy || e.Ticks != ticks {
+			t.Errorf("estimate %d: facility %d with %d own and %d enemy vehicles in %v ticks, want facility %d with %d and %d in %v",
+				i, e.Facility.Id, e.Own, e.Enemy, e.Ticks, w.id, w.own, w.enemy, ticks)
+		}
+	}
+}
+
+func TestFacilityProductionTicks(t *testing.T) {
+	g := DefaultGame(1)
+	p := NewFacilityPlanner(g)
+
+	tests := []struct {
+		name     string
+		facility *Facility
+		want     int
+	}{
+		{"control centre", &Facility{FacilityType: Facility_ControlCenter, VehicleType: Vehicle_Tank}, -1},
+		{"idle factory", &Facility{FacilityType: Facility_VehicleFactory, VehicleType: Vehicle_None}, -1},
+		{"just started", &Facility{FacilityType: Facility_VehicleFactory, VehicleType: Vehicle_Tank}, g.TankProductionCost},
+		{"in progress", &Facility{FacilityType: Facility_VehicleFactory, VehicleType: Vehicle_Tank, ProductionProgress: 20}, g.TankProductionCost - 20},
+		{"overdue", &Facility{FacilityType: Facility_VehicleFactory, VehicleType: Vehicle_Tank, ProductionProgress: g.TankProductionCost + 5}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := p.ProductionTicks(tt.facility); got != tt.want {
+			t.Errorf("%s: ProductionTicks = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFacilityRecommend(t *testing.T) {
+	g := DefaultGame(1)
+	p := NewFacilityPlanner(g)
+
+	// Every type is countered by the one it can't fight back against well.
+	counters := map[VehicleType]VehicleType{
+		Vehicle_Fighter:    Vehicle_Ifv,
+		Vehicle_Helicopter: Vehicle_Fighter,
+		Vehicle_Ifv:        Vehicle_Tank,
+		Vehicle_Tank:       Vehicle_Helicopter,
+	}
+	for enemy, want := range counters {
+		var enemies []*Vehicle
+		for i := 0; i < 10; i++ {
+			enemies = append(enemies, g.NewVehicle(enemy))
+		}
+		if got := p.Recommend(enemies); got != want {
+			t.Errorf("against %v: Recommend = %v, want %v", enemy, got, want)
+		}
+		if got := TypeDamagePerTick(g, want, enemy); got <= 0 {
+			t.Errorf("%v recommended against %v deals %v damage per tick", want, enemy, got)
+		}
+	}
+}
+
+func TestFacilityProduction(t *testing.T) {
+	g := DefaultGame(1)
+	p := NewFacilityPlanner(g)
+
+	facilities := []*Facility{
+		{Id: 1, FacilityType: Facility_VehicleFactory, OwnerPlayerId: 1, VehicleType: Vehicle_Tank, ProductionProgress: 10},
+		{Id: 2, FacilityType: Facility_VehicleFactory, OwnerPlayerId: 1, VehicleType: Vehicle_Helicopter},
+		{Id: 3, FacilityType: Facility_VehicleFactory, OwnerPlayerId: 2, VehicleType: Vehicle_Tank},
+		{Id: 4, FacilityType: Facility_ControlCenter, OwnerPlayerId: 1},
+	}
+	enemy := g.NewVehicle(Vehicle_Tank)
+	enemy.Id, enemy.PlayerId = 1, 2
+
+	plans := p.Production(facilityWorld(facilities, enemy))
+	if len(plans) != 2 || plans[0].Facility.Id != 1 || plans[1].Facility.Id != 2 {
+		t.Fatalf("plans %+v, want one for each of our factories", plans)
+	}
+	for _, plan := range plans {
+		if plan.Recommended != Vehicle_Helicopter {
+			t.Errorf("factory %d: recommended %v against tanks, want helicopters", plan.Facility.Id, plan.Recommended)
+		}
+	}
+	if !plans[0].Switch || plans[0].Ticks != g.TankProductionCost-10 || plans[1].Switch {
+		t.Errorf("plans %+v, %+v: want only the tank factory to switch", plans[0], plans[1])
+	}
+
+	m := NewMove()
+	plans[0].Move(m)
+	if m.Action != Action_SetupVehicleProduction || m.FacilityId != 1 || m.Type != Vehicle_Helicopter {
+		t.Errorf("move %+v, want the helicopter production set up in factory 1", m)
+	}
+}
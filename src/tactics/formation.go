@@ -0,0 +1,557 @@
+package tactics
+
+import (
+	"fmt"
+	"math"
+	. "model"
+)
+
+type FormationKind int
+
+const (
+	// Formation_Line puts the groups side by side across the heading.
+	Formation_Line FormationKind = iota
+
+	// Formation_Wedge puts the first group at the tip and the others behind
+	// it, alternately on the right and on the left.
+	Formation_Wedge
+
+	// Formation_Sandwich lines up the ground groups and puts the aerial
+	// groups right over them.
+	Formation_Sandwich
+
+	// Formation_Square packs the groups into a square.
+	Formation_Square
+)
+
+var formationKindNames = []string{"line", "wedge", "sandwich", "square"}
+
+func (k FormationKind) String() string {
+	if k >= 0 && int(k) < len(formationKindNames) {
+		return formationKindNames[k]
+	}
+	return "FormationKind(?)"
+}
+
+// Slot is the place of one group in a formation.
+type Slot struct {
+	Group int
+	X, Y  float64
+
+	// Side is the size of the group once compacted.
+	Side float64
+
+	Aerial bool
+}
+
+// Formation arranges groups of our vehicles. Fill in the exported fields and
+// pass it to FormationBuilder.Build, then push the returned order to the
+// scheduler and watch Progress or Complete.
+type Formation struct {
+	Kind   FormationKind
+	Groups []int
+
+	// X, Y is the centre of the formation, the position of the tip for
+	// Formation_Wedge.
+	X, Y float64
+
+	// Angle is the direction the formation faces, clockwise from the x axis.
+	// The groups are also rotated by it unless it is 0.
+	Angle float64
+
+	// MaxSpeed and MaxAngularSpeed limit the moves and rotations, 0 for no
+	// limit.
+	MaxSpeed        float64
+	MaxAngularSpeed float64
+
+	// Tolerance is how far a group may be off its slot for the formation to
+	// be complete.
+	Tolerance float64
+
+	// Slots are set by FormationBuilder.Build.
+	Slots []Slot
+}
+
+// Progress returns the share of the groups that reached their slots.
+func (f *Formation) Progress(w *World) float64 {
+	if len(f.Slots) == 0 {
+		return 0
+	}
+
+	n := 0
+	for _, s := range f.Slots {
+		if f.inPlace(w, s) {
+			n++
+		}
+	}
+	return float64(n) / float64(len(f.Slots))
+}
+
+// Complete reports whether every group reached its slot.
+func (f *Formation) Complete(w *World) bool {
+	return len(f.Slots) > 0 && f.Progress(w) == 1
+}
+
+// inPlace reports whether the group is centred on its slot and compacted to
+// its side, both within Tolerance.
+func (f *Formation) inPlace(w *World, s Slot) bool {
+	x, y, size, ok := groupExtent(w, s.Group, f.Angle)
+	return ok && math.Hypot(x-s.X, y-s.Y) <= f.Tolerance && size <= s.Side+f.Tolerance
+}
+
+// FormationBuilder plans the moves that arrange groups into formations.
+type FormationBuilder struct {
+	// Spacing is the distance between the centres of neighbouring vehicles
+	// of a compacted group, Gap the free space between groups.
+	Spacing float64
+	Gap     float64
+
+	radius float64
+}
+
+func NewFormationBuilder(g *Game) *FormationBuilder {
+	return &FormationBuilder{
+		Spacing: g.VehicleRadius * 2.5,
+		Gap:     g.VehicleRadius * 6,
+		radius:  g.VehicleRadius,
+	}
+}
+
+// Build sets the slots of the formation and returns the order arranging the
+// groups. The order rotates every group with Action_Rotate if the formation
+// has an angle, compacts them with Action_Scale, moves them to their slots
+// and compacts them again there, since a group loses its shape when its
+// vehicles cross different terrain. Each phase waits until the groups stop.
+// Ground vehicles block each other, and so do aerial ones, so a group only
+// sets off to its slot once its way is clear of the other groups of its
+// kind. Groups whose ways all cross may still get stuck; Progress tells how
+// far the formation got. Build fails if a group has no vehicles.
+func (b *FormationBuilder) Build(w *World, f *Formation) (*Order, error) {
+	if f.Tolerance == 0 {
+		f.Tolerance = b.Spacing
+	}
+
+	slots, err := b.slots(w, f)
+	if err != nil {
+		return nil, err
+	}
+	f.Slots = slots
+
+	var steps []Step
+
+	if angle := math.Remainder(f.Angle, 2*math.Pi); angle != 0 {
+		steps = append(steps, phase(f, func(w *World, s Slot, m *Move) error {
+			gb, ok := groupBounds(w, s.Group)
+			if !ok {
+				return emptyGroup(s.Group)
+			}
+			m.Action = Action_Rotate
+			m.X, m.Y = gb.center()
+			m.Angle = angle
+			m.MaxSpeed = f.MaxSpeed
+			m.MaxAngularSpeed = f.MaxAngularSpeed
+			return nil
+		})...)
+	}
+
+	steps = append(steps, phase(f, func(w *World, s Slot, m *Move) error {
+		return b.compact(w, f, s, false, m)
+	})...)
+	steps = append(steps, (&arrangement{formation: f, pending: slots, margin: b.Gap}).steps()...)
+	steps = append(steps, phase(f, func(w *World, s Slot, m *Move) error {
+		return b.compact(w, f, s, true, m)
+	})...)
+
+	return &Order{Steps: steps}, nil
+}
+
+// phase returns the steps selecting every group of the formation in turn and
+// giving it the move built by build. The phase starts once the groups stop.
+func phase(f *Formation, build func(w *World, s Slot, m *Move) error) []Step {
+	settled := Settled(f.Groups...)
+
+	steps := make([]Step, 0, 2*len(f.Slots))
+	for i, s := range f.Slots {
+		s := s
+		sel := Step{
+			Build: func(w *World, m *Move) error {
+				m.Action = Action_ClearAndSelect
+				m.Group = s.Group
+				return nil
+			},
+		}
+		if i == 0 {
+			sel.Ready = settled
+		}
+		steps = append(steps, sel, Step{
+			Build: func(w *World, m *Move) error { return build(w, s, m) },
+		})
+	}
+	return steps
+}
+
+// compact fills m with the Action_Scale shrinking the group to the side of
+// its slot, around its centre or around the slot. A group that is compact
+// already is left alone.
+func (b *FormationBuilder) compact(w *World, f *Formation, s Slot, atSlot bool, m *Move) error {
+	x, y, size, ok := groupExtent(w, s.Group, f.Angle)
+	if !ok {
+		return emptyGroup(s.Group)
+	}
+
+	if factor := (s.Side - 2*b.radius) / (size - 2*b.radius); factor < 1 {
+		m.Action = Action_Scale
+		m.X, m.Y = x, y
+		if atSlot {
+			m.X, m.Y = s.X, s.Y
+		}
+		m.Factor = math.Max(0.1, factor)
+		m.MaxSpeed = f.MaxSpeed
+	}
+	return nil
+}
+
+// slots places the groups in the frame of the formation, forward along its
+// heading and right across it.
+func (b *FormationBuilder) slots(w *World, f *Formation) ([]Slot, error) {
+	var ground, aerial []Slot
+	pitch := 0.0
+
+	for _, group := range f.Groups {
+		n, air := 0, 0
+		for _, v := range w.Vehicles.Mine() {
+			if v.InGroup(group) {
+				n++
+				if v.Aerial {
+					air++
+				}
+			}
+		}
+		if n == 0 {
+			return nil, emptyGroup(group)
+		}
+
+		s := Slot{Group: group, Side: b.side(n), Aerial: 2*air > n}
+		pitch = math.Max(pitch, s.Side+b.Gap)
+		if s.Aerial {
+			aerial = append(aerial, s)
+		} else {
+			ground = append(ground, s)
+		}
+	}
+
+	// offsets in units of pitch, forward and right
+	var offsets [][2]float64
+	line := func(n int) {
+		for i := 0; i < n; i++ {
+			offsets = append(offsets, [2]float64{0, float64(i) - float64(n-1)/2})
+		}
+	}
+
+	var slots []Slot
+	switch f.Kind {
+	case Formation_Sandwich:
+		if len(ground) == 0 {
+			ground, aerial = aerial, nil
+		}
+		slots = append(ground, aerial...)
+		line(len(ground))
+		for i := range aerial {
+			offsets = append(offsets, offsets[i%len(ground)])
+		}
+
+	case Formation_Wedge:
+		slots = b.inOrder(f.Groups, ground, aerial)
+		for i := range slots {
+			row := float64((i + 1) / 2)
+			side := row
+			if i%2 == 0 {
+				side = -row
+			}
+			offsets = append(offsets, [2]float64{-row, side})
+		}
+
+	case Formation_Square:
+		slots = b.inOrder(f.Groups, ground, aerial)
+		k := int(math.Ceil(math.Sqrt(float64(len(slots)))))
+		for i := range slots {
+			r, c := i/k, i%k
+			offsets = append(offsets, [2]float64{float64(k-1)/2 - float64(r), float64(c) - float64(k-1)/2})
+		}
+
+	default:
+		slots = b.inOrder(f.Groups, ground, aerial)
+		line(len(slots))
+	}
+
+	sin, cos := math.Sincos(f.Angle)
+	for i := range slots {
+		fwd, right := offsets[i][0]*pitch, offsets[i][1]*pitch
+		slots[i].X = f.X + fwd*cos - right*sin
+		slots[i].Y = f.Y + fwd*sin + right*cos
+	}
+	return slots, nil
+}
+
+// inOrder returns the slots in the order of the groups.
+func (b *FormationBuilder) inOrder(groups []int, ground, aerial []Slot) []Slot {
+	byGroup := make(map[int]Slot, len(groups))
+	for _, s := range append(ground, aerial...) {
+		byGroup[s.Group] = s
+	}
+	slots := make([]Slot, 0, len(groups))
+	for _, group := range groups {
+		slots = append(slots, byGroup[group])
+	}
+	return slots
+}
+
+// side returns the size of a square of n vehicles at Spacing.
+func (b *FormationBuilder) side(n int) float64 {
+	cols := math.Ceil(math.Sqrt(float64(n)))
+	return (cols-1)*b.Spacing + 2*b.radius
+}
+
+// arrangement moves the groups of a formation to their slots, each once its
+// way is clear.
+type arrangement struct {
+	formation *Formation
+
+	// margin is the free space kept between groups on the way.
+	margin float64
+
+	pending []Slot
+	sent    []Slot
+	next    Slot
+	settled func(w *World) bool
+}
+
+func (a *arrangement) steps() []Step {
+	a.settled = Settled(a.formation.Groups...)
+
+	steps := make([]Step, 0, 2*len(a.pending))
+	for range a.pending {
+		steps = append(steps, Step{Ready: a.ready, Build: a.selectNext}, Step{Build: a.move})
+	}
+	return steps
+}
+
+// ready picks the next group to move: the first pending group whose way is
+// clear and whose slot isn't in the way of the other pending groups. Once
+// every group stopped, a group in the way or with its way blocked goes
+// anyway.
+func (a *arrangement) ready(w *World) bool {
+	settled := a.settled(w)
+
+	var clear []Slot
+	for _, s := range a.pending {
+		if a.clear(w, s) {
+			clear = append(clear, s)
+		}
+	}
+	for _, s := range clear {
+		if !a.inTheWay(w, s) {
+			a.next = s
+			return true
+		}
+	}
+	if !settled {
+		return false
+	}
+
+	a.next = a.pending[0]
+	if len(clear) > 0 {
+		a.next = clear[0]
+	}
+	return true
+}
+
+// inTheWay reports whether the slot lies on the way of another pending group
+// of the same kind.
+func (a *arrangement) inTheWay(w *World, s Slot) bool {
+	for _, o := range a.pending {
+		if o.Group == s.Group || o.Aerial != s.Aerial {
+			continue
+		}
+		if b, ok := groupBounds(w, o.Group); ok {
+			x, y := b.center()
+			if segmentDistance(s.X, s.Y, x, y, o.X, o.Y) < b.radius()+s.Side/2+a.margin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clear reports whether the group can go straight to its slot without
+// running into the other groups of its kind, on their way to their slots for
+// the groups sent already.
+func (a *arrangement) clear(w *World, s Slot) bool {
+	b, ok := groupBounds(w, s.Group)
+	if !ok {
+		return true
+	}
+	x0, y0 := b.center()
+
+	sent := make(map[int]Slot, len(a.sent))
+	for _, o := range a.sent {
+		sent[o.Group] = o
+	}
+
+	for _, o := range a.formation.Slots {
+		if o.Group == s.Group || o.Aerial != s.Aerial {
+			continue
+		}
+		ob, ok := groupBounds(w, o.Group)
+		if !ok {
+			continue
+		}
+		x1, y1 := ob.center()
+		x2, y2, r := x1, y1, ob.radius()
+		if _, ok := sent[o.Group]; ok {
+			x2, y2, r = o.X, o.Y, math.Max(r, o.Side/2)
+		}
+		if segmentsDistance(x0, y0, s.X, s.Y, x1, y1, x2, y2) < b.radius()+r+a.margin {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *arrangement) selectNext(w *World, m *Move) error {
+	for i, s := range a.pending {
+		if s.Group == a.next.Group {
+			a.pending = append(a.pending[:i:i], a.pending[i+1:]...)
+			break
+		}
+	}
+	a.sent = append(a.sent, a.next)
+
+	m.Action = Action_ClearAndSelect
+	m.Group = a.next.Group
+	return nil
+}
+
+func (a *arrangement) move(w *World, m *Move) error {
+	s := a.next
+	b, ok := groupBounds(w, s.Group)
+	if !ok {
+		return emptyGroup(s.Group)
+	}
+	cx, cy := b.center()
+	m.Action = Action_Move
+	m.X, m.Y = s.X-cx, s.Y-cy
+	m.MaxSpeed = a.formation.MaxSpeed
+	return nil
+}
+
+// segmentDistance returns the distance from (px, py) to the segment between
+// (x0, y0) and (x1, y1).
+func segmentDistance(px, py, x0, y0, x1, y1 float64) float64 {
+	dx, dy := x1-x0, y1-y0
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((px-x0)*dx+(py-y0)*dy)/l))
+	}
+	return math.Hypot(px-(x0+t*dx), py-(y0+t*dy))
+}
+
+// segmentsDistance returns the distance between the segments a0-a1 and b0-b1.
+func segmentsDistance(ax0, ay0, ax1, ay1, bx0, by0, bx1, by1 float64) float64 {
+	cross := func(ox, oy, px, py, qx, qy float64) float64 {
+		return (px-ox)*(qy-oy) - (py-oy)*(qx-ox)
+	}
+	d1, d2 := cross(bx0, by0, bx1, by1, ax0, ay0), cross(bx0, by0, bx1, by1, ax1, ay1)
+	d3, d4 := cross(ax0, ay0, ax1, ay1, bx0, by0), cross(ax0, ay0, ax1, ay1, bx1, by1)
+	if (d1 > 0) != (d2 > 0) && (d3 > 0) != (d4 > 0) && d1 != 0 && d2 != 0 && d3 != 0 && d4 != 0 {
+		return 0
+	}
+
+	return math.Min(
+		math.Min(segmentDistance(ax0, ay0, bx0, by0, bx1, by1), segmentDistance(ax1, ay1, bx0, by0, bx1, by1)),
+		math.Min(segmentDistance(bx0, by0, ax0, ay0, ax1, ay1), segmentDistance(bx1, by1, ax0, ay0, ax1, ay1)))
+}
+
+// Settled is a precondition met once no vehicle of the groups moved since it
+// was last checked on an earlier tick.
+func Settled(groups ...int) func(w *World) bool {
+	var last map[int64][2]float64
+	tick, result := -1, false
+
+	return func(w *World) bool {
+		if w.TickIndex == tick {
+			return result
+		}
+
+		current := make(map[int64][2]float64)
+		for _, v := range w.Vehicles.Mine() {
+			for _, group := range groups {
+				if v.InGroup(group) {
+					current[v.Id] = [2]float64{v.X, v.Y}
+					break
+				}
+			}
+		}
+
+		result = last != nil && len(current) == len(last)
+		for id, p := range current {
+			result = result && last[id] == p
+		}
+		last, tick = current, w.TickIndex
+		return result
+	}
+}
+
+func emptyGroup(group int) error {
+	return fmt.Errorf("tactics: group %d has no vehicles", group)
+}
+
+type bounds struct {
+	left, top, right, bottom float64
+}
+
+func (b bounds) center() (x, y float64) {
+	return (b.left + b.right) / 2, (b.top + b.bottom) / 2
+}
+
+// radius returns the half of the longer side.
+func (b bounds) radius() float64 {
+	return math.Max(b.right-b.left, b.bottom-b.top) / 2
+}
+
+// groupExtent returns the centre of the group and its size along the axes
+// turned by angle, counting the radius of the vehicles.
+func groupExtent(w *World, group int, angle float64) (x, y, size float64, ok bool) {
+	b, ok := groupBounds(w, group)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	x, y = b.center()
+
+	sin, cos := math.Sincos(angle)
+	var fwd, right bounds
+	for _, v := range w.Vehicles.Mine() {
+		if v.InGroup(group) {
+			f, r := (v.X-x)*cos+(v.Y-y)*sin, (v.Y-y)*cos-(v.X-x)*sin
+			fwd.left, fwd.right = math.Min(fwd.left, f), math.Max(fwd.right, f)
+			right.left, right.right = math.Min(right.left, r), math.Max(right.right, r)
+			size = math.Max(size, v.Radius)
+		}
+	}
+	return x, y, math.Max(fwd.right-fwd.left, right.right-right.left) + 2*size, true
+}
+
+func groupBounds(w *World, group int) (b bounds, ok bool) {
+	for _, v := range w.Vehicles.Mine() {
+		if !v.InGroup(group) {
+			continue
+		}
+		if !ok {
+			b, ok = bounds{v.X, v.Y, v.X, v.Y}, true
+			continue
+		}
+		b.left, b.right = math.Min(b.left, v.X), math.Max(b.right, v.X)
+		b.top, b.bottom = math.Min(b.top, v.Y), math.Max(b.bottom, v.Y)
+	}
+	return b, ok
+}
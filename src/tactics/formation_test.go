@@ -0,0 +1,156 @@
+package tactics
+
+import (
+	"math"
+	. "model"
+	"strings"
+	"testing"
+)
+
+// formationGroup describes a group of 9 vehicles in a 3 by 3 square with
+// the given spacing around the centre.
+type formationGroup struct {
+	group   int
+	vt      VehicleType
+	x, y    float64
+	spacing float64
+}
+
+func formationWorld(g *Game, groups ...formationGroup) *World {
+	w := &World{
+		Players:  []*Player{{Id: 1, Me: true}, {Id: 2}},
+		Vehicles: NewVehicleRegistry(),
+	}
+	for _, fg := range groups {
+		for i := 0; i < 9; i++ {
+			v := g.NewVehicle(fg.vt)
+			v.Id, v.PlayerId, v.Groups = int64(len(w.NewVehicles)+1), 1, []int{fg.group}
+			v.X = fg.x + float64(i%3-1)*fg.spacing
+			v.Y = fg.y + float64(i/3-1)*fg.spacing
+			w.NewVehicles = append(w.NewVehicles, v)
+		}
+	}
+	w.Vehicles.Update(w)
+	return w
+}
+
+func TestFormationSlots(t *testing.T) {
+	g := DefaultGame(1)
+	b := NewFormationBuilder(g)
+	// A compacted group of 9 is 3 vehicles across: its side is 2 spacings
+	// and a vehicle, and neighbouring slots are a side and a gap apart.
+	side := 2*b.Spacing + 2*g.VehicleRadius
+	pitch := side + b.Gap
+
+	ground := []formationGroup{
+		{1, Vehicle_Tank, 100, 100, 10},
+		{2, Vehicle_Ifv, 200, 100, 10},
+		{3, Vehicle_Arrv, 300, 100, 10},
+	}
+	sandwich := []formationGroup{ground[0], ground[1], {4, Vehicle_Fighter, 400, 100, 10}}
+
+	tests := []struct {
+		name   string
+		kind   FormationKind
+		angle  float64
+		groups []formationGroup
+		want   [][2]float64
+	}{
+		{"line", Formation_Line, 0, ground, [][2]float64{{500, 500 - pitch}, {500, 500}, {500, 500 + pitch}}},
+		{"turned line", Formation_Line, math.Pi / 2, ground, [][2]float64{{500 + pitch, 500}, {500, 500}, {500 - pitch, 500}}},
+		{"wedge", Formation_Wedge, 0, ground, [][2]float64{{500, 500}, {500 - pitch, 500 + pitch}, {500 - pitch, 500 - pitch}}},
+		{"square", Formation_Square, 0, ground, [][2]float64{
+			{500 + pitch/2, 500 - pitch/2}, {500 + pitch/2, 500 + pitch/2}, {500 - pitch/2, 500 - pitch/2}}},
+		{"sandwich", Formation_Sandwich, 0, sandwich, [][2]float64{
+			{500, 500 - pitch/2}, {500, 500 + pitch/2}, {500, 500 - pitch/2}}},
+	}
+
+	for _, tt := range tests {
+		f := &Formation{Kind: tt.kind, X: 500, Y: 500, Angle: tt.angle}
+		for _, fg := range tt.groups {
+			f.Groups = append(f.Groups, fg.group)
+		}
+		if _, err := b.Build(formationWorld(g, tt.groups...), f); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if len(f.Slots) != len(tt.want) {
+			t.Errorf("%s: %d slots, want %d", tt.name, len(f.Slots), len(tt.want))
+			continue
+		}
+		for i, s := range f.Slots {
+			if s.Group != tt.groups[i].group || math.Abs(s.X-tt.want[i][0]) > 1e-9 || math.Abs(s.Y-tt.want[i][1]) > 1e-9 {
+				t.Errorf("%s: slot %d of group %d at %v, %v, want group %d at %v",
+					tt.name, i, s.Group, s.X, s.Y, tt.groups[i].group, tt.want[i])
+			}
+			if s.Side != side || s.Aerial != (tt.groups[i].vt == Vehicle_Fighter) {
+				t.Errorf("%s: slot %d: %+v, want side %v", tt.name, i, s, side)
+			}
+		}
+	}
+}
+
+func TestFormationEmptyGroup(t *testing.T) {
+	g := DefaultGame(1)
+	f := &Formation{Groups: []int{1, 7}}
+	_, err := NewFormationBuilder(g).Build(formationWorld(g, formationGroup{1, Vehicle_Tank, 100, 100, 10}), f)
+	if err == nil || !strings.Contains(err.Error(), "group 7") {
+		t.Errorf("Build with an empty group: %v, want an error naming it", err)
+	}
+}
+
+func TestFormationComplete(t *testing.T) {
+	g := DefaultGame(1)
+	b := NewFormationBuilder(g)
+
+	f := &Formation{Kind: Formation_Line, Groups: []int{1, 2, 3}, X: 500, Y: 500}
+	start := formationWorld(g,
+		formationGroup{1, Vehicle_Tank, 100, 100, 10},
+		formationGroup{2, Vehicle_Tank, 200, 100, 10},
+		formationGroup{3, Vehicle_Tank, 300, 100, 10})
+	if _, err := b.Build(start, f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Tolerance != b.Spacing {
+		t.Errorf("default Tolerance %v, want the spacing %v", f.Tolerance, b.Spacing)
+	}
+	if f.Complete(start) || f.Progress(start) != 0 {
+		t.Errorf("formation complete before moving: progress %v", f.Progress(start))
+	}
+
+	// at places the groups on their slots, off by the given distances and
+	// with the given spacings.
+	at := func(offsets, spacings [3]float64) *World {
+		var groups []formationGroup
+		for i, s := range f.Slots {
+			groups = append(groups, formationGroup{s.Group, Vehicle_Tank, s.X + offsets[i], s.Y, spacings[i]})
+		}
+		return formationWorld(g, groups...)
+	}
+	compact := [3]float64{b.Spacing, b.Spacing, b.Spacing}
+
+	tests := []struct {
+		name     string
+		w        *World
+		progress float64
+	}{
+		{"in place", at([3]float64{}, compact), 1},
+		{"within the tolerance", at([3]float64{f.Tolerance, -f.Tolerance / 2}, compact), 1},
+		{"one group off its slot", at([3]float64{0, 0, 2 * f.Tolerance}, compact), 2.0 / 3},
+		{"one group spread out", at([3]float64{}, [3]float64{b.Spacing, 2 * b.Spacing, b.Spacing}), 2.0 / 3},
+	}
+
+	for _, tt := range tests {
+		if p := f.Progress(tt.w); math.Abs(p-tt.progress) > 1e-9 {
+			t.Errorf("%s: Progress = %v, want %v", tt.name, p, tt.progress)
+		}
+		if c := f.Complete(tt.w); c != (tt.progress == 1) {
+			t.Errorf("%s: Complete = %v", tt.name, c)
+		}
+	}
+
+	if (&Formation{}).Complete(start) {
+		t.Error("formation without slots is complete")
+	}
+}
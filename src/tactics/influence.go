@@ -0,0 +1,213 @@
+package tactics
+
+import (
+	"math"
+	. "model"
+	"pathfinding"
+)
+
+// Influence is what the influence map knows about one cell. The damage
+// potentials are the damage per tick the vehicles able to shoot into the
+// cell deal to a ground or an aerial target there, before its defence.
+type Influence struct {
+	FriendlyGround, FriendlyAerial float64
+	EnemyGround, EnemyAerial       float64
+
+	// EnemyValue is the production cost of the enemy vehicles in the cell,
+	// weighted by their remaining durability.
+	EnemyValue float64
+}
+
+// Threat returns the enemy damage potential against a target of the given
+// kind.
+func (c *Influence) Threat(aerial bool) float64 {
+	if aerial {
+		return c.EnemyAerial
+	}
+	return c.EnemyGround
+}
+
+// InfluenceMap splits the world into square cells and keeps the influence of
+// both armies on each of them. Call Update once per tick.
+type InfluenceMap struct {
+	// ThreatWeight scales how much enemy damage potential costs compared to
+	// distance when searching for safe paths.
+	ThreatWeight float64
+
+	game       *Game
+	cellSize   float64
+	cols, rows int
+	cells      []Influence
+}
+
+func NewInfluenceMap(g *Game, cellSize float64) *InfluenceMap {
+	cols := int(math.Ceil(g.WorldWidth / cellSize))
+	rows := int(math.Ceil(g.WorldHeight / cellSize))
+	return &InfluenceMap{
+		ThreatWeight: 1,
+		game:         g,
+		cellSize:     cellSize,
+		cols:         cols,
+		rows:         rows,
+		cells:        make([]Influence, cols*rows),
+	}
+}
+
+// Update rebuilds the map from our vehicles, the visible enemies and, if
+// memory isn't nil, the enemies hidden by the fog of war at their last known
+// positions.
+func (m *InfluenceMap) Update(w *World, memory *EnemyMemory) {
+	for i := range m.cells {
+		m.cells[i] = Influence{}
+	}
+
+	for _, v := range w.Vehicles.Mine() {
+		m.add(v, false)
+	}
+	for _, v := range w.Vehicles.Enemy() {
+		m.add(v, true)
+	}
+	if memory != nil {
+		for _, s := range memory.Hidden() {
+			if !s.Stale {
+				m.add(&s.Vehicle, true)
+			}
+		}
+	}
+}
+
+func (m *InfluenceMap) add(v *Vehicle, enemy bool) {
+	cooldown := float64(v.AttackCooldownTicks)
+	if cooldown < 1 {
+		cooldown = 1
+	}
+	ground := float64(v.GroundDamage) / cooldown
+	aerial := float64(v.AerialDamage) / cooldown
+
+	if enemy {
+		c := &m.cells[m.index(v.X, v.Y)]
+		c.EnemyValue += float64(m.game.ProductionCost(v.Type)) * float64(v.Durability) / math.Max(1, float64(v.MaxDurability))
+	}
+
+	// A cell is in reach if its centre is within the range plus half of the
+	// cell diagonal.
+	slack := m.cellSize * math.Sqrt2 / 2
+	r := math.Max(v.GroundAttackRange, v.AerialAttackRange) + slack
+	x0, y0 := m.cell(v.X-r, v.Y-r)
+	x1, y1 := m.cell(v.X+r, v.Y+r)
+
+	for cy := y0; cy <= y1; cy++ {
+		for cx := x0; cx <= x1; cx++ {
+			px, py := m.Center(cx, cy)
+			d := v.GetDistanceTo(px, py)
+			c := &m.cells[cy*m.cols+cx]
+
+			if ground > 0 && d <= v.GroundAttackRange+slack {
+				if enemy {
+					c.EnemyGround += ground
+				} else {
+					c.FriendlyGround += ground
+				}
+			}
+			if aerial > 0 && d <= v.AerialAttackRange+slack {
+				if enemy {
+					c.EnemyAerial += aerial
+				} else {
+					c.FriendlyAerial += aerial
+				}
+			}
+		}
+	}
+}
+
+func (m *InfluenceMap) cell(x, y float64) (cx, cy int) {
+	cx, cy = int(x/m.cellSize), int(y/m.cellSize)
+	return clampIndex(cx, m.cols), clampIndex(cy, m.rows)
+}
+
+func clampIndex(c, n int) int {
+	if c < 0 {
+		return 0
+	}
+	if c >= n {
+		return n - 1
+	}
+	return c
+}
+
+func (m *InfluenceMap) index(x, y float64) int {
+	cx, cy := m.cell(x, y)
+	return cy*m.cols + cx
+}
+
+// Center returns the centre of the cell.
+func (m *InfluenceMap) Center(cx, cy int) (x, y float64) {
+	return (float64(cx) + 0.5) * m.cellSize, (float64(cy) + 0.5) * m.cellSize
+}
+
+// At returns the influence at the point.
+func (m *InfluenceMap) At(x, y float64) *Influence {
+	return &m.cells[m.index(x, y)]
+}
+
+// SafestPath returns the points, cell centres followed by the destination,
+// of the path that minimises the distance plus the enemy threat against a
+// target of the given kind along the way, weighted by ThreatWeight.
+func (m *InfluenceMap) SafestPath(fromX, fromY, toX, toY float64, aerial bool) [][2]float64 {
+	start, goal := m.index(fromX, fromY), m.index(toX, toY)
+
+	cells := pathfinding.Cheapest(m.cols, m.rows, start, goal, func(from, to int) float64 {
+		d := m.cellSize
+		if from%m.cols != to%m.cols && from/m.cols != to/m.cols {
+			d *= math.Sqrt2
+		}
+		return d * (1 + m.ThreatWeight*m.cells[to].Threat(aerial))
+	})
+	if cells == nil {
+		return nil
+	}
+	if len(cells) < 2 {
+		return [][2]float64{{toX, toY}}
+	}
+
+	var path [][2]float64
+	for _, c := range cells[1 : len(cells)-1] {
+		x, y := m.Center(c%m.cols, c/m.cols)
+		path = append(path, [2]float64{x, y})
+	}
+	return append(path, [2]float64{toX, toY})
+}
+
+// HighestEnemyValue returns the point where the enemy vehicles within radius
+// are worth the most, minus the threat they pose to a target of the given
+// kind weighted by ThreatWeight, and that score. ok is false if no enemy is
+// known.
+func (m *InfluenceMap) HighestEnemyValue(radius float64, aerial bool) (x, y, score float64, ok bool) {
+	reach := int(math.Ceil(radius / m.cellSize))
+	score = math.Inf(-1)
+
+	for cy := 0; cy < m.rows; cy++ {
+		for cx := 0; cx < m.cols; cx++ {
+			px, py := m.Center(cx, cy)
+
+			value := 0.0
+			for ny := clampIndex(cy-reach, m.rows); ny <= clampIndex(cy+reach, m.rows); ny++ {
+				for nx := clampIndex(cx-reach, m.cols); nx <= clampIndex(cx+reach, m.cols); nx++ {
+					qx, qy := m.Center(nx, ny)
+					if math.Hypot(qx-px, qy-py) <= radius {
+						value += m.cells[ny*m.cols+nx].EnemyValue
+					}
+				}
+			}
+			if value == 0 {
+				continue
+			}
+
+			if s := value - m.ThreatWeight*m.cells[cy*m.cols+cx].Threat(aerial); s > score {
+				x, y, score, ok = px, py, s, true
+			}
+		}
+	}
+
+	return x, y, score, ok
+}
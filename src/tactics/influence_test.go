@@ -0,0 +1,37 @@
+package tactics
+
+import (
+	. "model"
+	"testing"
+)
+
+func TestSafestPathEndpoints(t *testing.T) {
+	m := NewInfluenceMap(DefaultGame(1), 32)
+
+	tests := []struct {
+		name                   string
+		fromX, fromY, toX, toY float64
+		want                   [][2]float64
+	}{
+		{"same cell", 100, 100, 110, 110, [][2]float64{{110, 110}}},
+		{"same point", 100, 100, 100, 100, [][2]float64{{100, 100}}},
+		{"adjacent cells", 100, 100, 140, 100, [][2]float64{{140, 100}}},
+		{"one cell between", 100, 100, 170, 100, [][2]float64{{144, 112}, {170, 100}}},
+	}
+
+	for _, tt := range tests {
+		for _, aerial := range []bool{false, true} {
+			got := m.SafestPath(tt.fromX, tt.fromY, tt.toX, tt.toY, aerial)
+			if len(got) != len(tt.want) {
+				t.Errorf("%s, aerial %v: got %v, want %v", tt.name, aerial, got, tt.want)
+				continue
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s, aerial %v: got %v, want %v", tt.name, aerial, got, tt.want)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package tactics
+
+import (
+	"context"
+	"math"
+	. "model"
+	"sort"
+)
+
+// maxScaleFactor is the largest Action_Scale factor the server accepts.
+const maxScaleFactor = 10
+
+// StrikePlan is a tactical nuclear strike worth launching.
+type StrikePlan struct {
+	X, Y      float64
+	SpotterId int64
+
+	// EnemyDamage and FriendlyDamage are the durability the strike takes
+	// from enemy and own vehicles, Kills the number of enemies it destroys.
+	EnemyDamage    int
+	FriendlyDamage int
+	Kills          int
+}
+
+// Score is the damage dealt to the enemy minus the damage taken.
+func (p *StrikePlan) Score() int {
+	return p.EnemyDamage - p.FriendlyDamage
+}
+
+// Move fills m with the action launching the strike.
+func (p *StrikePlan) Move(m *Move) {
+	m.Action = Action_TacticalNuclearStrike
+	m.X, m.Y = p.X, p.Y
+	m.VehicleId = p.SpotterId
+}
+
+// NuclearPlanner chooses where to launch our nuclear strikes and evaluates the
+// ones the opponent announces. Damage falls off linearly from
+// TacticalNuclearStrikeMaxDamage at the centre to zero at
+// TacticalNuclearStrikeRadius. Vehicles are assumed to keep their positions
+// during the TacticalNuclearStrikeDelay.
+type NuclearPlanner struct {
+	// MinScore is the smallest Score of a plan worth launching.
+	MinScore int
+
+	game *Game
+
+	// maxVision and maxAttackRange are the largest ranges of any vehicle
+	// type, used to bound the queries.
+	maxVision      float64
+	maxAttackRange float64
+}
+
+func NewNuclearPlanner(g *Game) *NuclearPlanner {
+	return &NuclearPlanner{
+		MinScore:       1,
+		game:           g,
+		maxVision:      maxOverTypes(g, func(v *Vehicle) float64 { return v.VisionRange }),
+		maxAttackRange: maxOverTypes(g, func(v *Vehicle) float64 { return math.Max(v.GroundAttackRange, v.AerialAttackRange) }),
+	}
+}
+
+// Damage returns the durability v loses from a strike at (x, y).
+func (p *NuclearPlanner) Damage(x, y float64, v *Vehicle) int {
+	r := p.game.TacticalNuclearStrikeRadius
+	d := math.Sqrt(v.GetSquaredDistanceTo(x, y))
+	if d > r {
+		return 0
+	}
+	dmg := int(p.game.TacticalNuclearStrikeMaxDamage * (1 - d/r))
+	if dmg > v.Durability {
+		return v.Durability
+	}
+	return dmg
+}
+
+// Evaluate returns the effect of a strike at (x, y) on the indexed vehicles
+// for the player me.
+func (p *NuclearPlanner) Evaluate(index *SpatialIndex, me int64, x, y float64) *StrikePlan {
+	plan := &StrikePlan{X: x, Y: y, SpotterId: -1}
+
+	for _, v := range index.Radius(x, y, p.game.TacticalNuclearStrikeRadius, nil) {
+		dmg := p.Damage(x, y, v)
+		if v.PlayerId == me {
+			plan.FriendlyDamage += dmg
+			continue
+		}
+		plan.EnemyDamage += dmg
+		if dmg > 0 && dmg == v.Durability {
+			plan.Kills++
+		}
+	}
+
+	return plan
+}
+
+// Plan returns the best strike the player can launch now, or nil if it can't
+// launch one or no strike reaches MinScore. Candidate points are the visible
+// enemy vehicles and the centres of the enemies around them.
+func (p *NuclearPlanner) Plan(w *World, me *Player) *StrikePlan {
+	return p.PlanContext(context.Background(), w, me)
+}
+
+// PlanContext is Plan stopping early when ctx is done, with the best strike
+// among the candidates tried so far.
+func (p *NuclearPlanner) PlanContext(ctx context.Context, w *World, me *Player) *StrikePlan {
+	if me == nil || me.RemainingNuclearStrikeCooldownTicks > 0 || me.NextNuclearStrikeTickIndex >= 0 {
+		return nil
+	}
+
+	enemies := w.Vehicles.Enemy()
+	if len(enemies) == 0 {
+		return nil
+	}
+
+	index := NewSpatialIndex(w.Width, w.Height, p.game.TacticalNuclearStrikeRadius)
+	index.Build(w.Vehicles.All())
+
+	// Candidates closer than a tenth of the radius to one already tried
+	// are skipped, dense armies would otherwise be evaluated many times over.
+	step := p.game.TacticalNuclearStrikeRadius / 10
+	tried := make(map[[2]int]bool)
+
+	var best *StrikePlan
+	try := func(x, y float64) {
+		if x < 0 || x > w.Width || y < 0 || y > w.Height {
+			return
+		}
+		key := [2]int{int(x / step), int(y / step)}
+		if tried[key] {
+			return
+		}
+		tried[key] = true
+
+		plan := p.Evaluate(index, me.Id, x, y)
+		if plan.Score() < p.MinScore || best != nil && !better(plan, best) {
+			return
+		}
+		if plan.SpotterId = p.spotter(w, index, me.Id, x, y); plan.SpotterId >= 0 {
+			best = plan
+		}
+	}
+
+	for i, e := range enemies {
+		if i%16 == 0 && ctx.Err() != nil {
+			break
+		}
+		try(e.X, e.Y)
+
+		near := index.Radius(e.X, e.Y, p.game.TacticalNuclearStrikeRadius/2, NotOfPlayer(me.Id))
+		if len(near) > 1 {
+			cx, cy := centre(near)
+			try(cx, cy)
+		}
+	}
+
+	return best
+}
+
+func better(a, b *StrikePlan) bool {
+	if a.Score() != b.Score() {
+		return a.Score() > b.Score()
+	}
+	return a.Kills > b.Kills
+}
+
+func centre(vehicles []*Vehicle) (x, y float64) {
+	for _, v := range vehicles {
+		x += v.X
+		y += v.Y
+	}
+	n := float64(len(vehicles))
+	return x / n, y / n
+}
+
+// spotter returns the own vehicle best suited to direct a strike at (x, y), or
+// -1 if none can. A spotter must see the point from outside the blast radius
+// and survive the enemies that can shoot at it until the strike lands. Among
+// those the one seeing the point with the largest margin wins, so small
+// movements don't cancel the strike.
+func (p *NuclearPlanner) spotter(w *World, index *SpatialIndex, me int64, x, y float64) int64 {
+	var best *Vehicle
+	bestMargin := 0.0
+
+	for _, v := range index.Radius(x, y, p.maxVision, OfPlayer(me)) {
+		d := v.GetDistanceTo(x, y)
+		if d <= p.game.TacticalNuclearStrikeRadius {
+			continue
+		}
+
+		vision := 1.0
+		if w.Map != nil {
+			vision, _, _ = w.Map.VehicleFactors(v)
+		}
+		margin := v.VisionRange*vision - d
+		if margin < 0 || best != nil && margin <= bestMargin || !p.survives(index, v) {
+			continue
+		}
+		best, bestMargin = v, margin
+	}
+
+	if best == nil {
+		return -1
+	}
+	return best.Id
+}
+
+// survives reports whether the enemies in range of v can't destroy it during
+// the strike delay.
+func (p *NuclearPlanner) survives(index *SpatialIndex, v *Vehicle) bool {
+	damage := 0
+	for _, e := range index.Radius(v.X, v.Y, p.maxAttackRange, NotOfPlayer(v.PlayerId)) {
+		if CanAttack(e, v) {
+			damage += DamageWithin(e, v, p.game.TacticalNuclearStrikeDelay+1)
+		}
+	}
+	return damage < v.Durability
+}
+
+var vehicleTypes = []VehicleType{Vehicle_Arrv, Vehicle_Fighter, Vehicle_Helicopter, Vehicle_Ifv, Vehicle_Tank}
+
+// maxOverTypes returns the largest value of f over vehicles of every type.
+func maxOverTypes(g *Game, f func(*Vehicle) float64) float64 {
+	max := 0.0
+	for _, t := range vehicleTypes {
+		max = math.Max(max, f(g.NewVehicle(t)))
+	}
+	return max
+}
+
+// Risk is an own vehicle caught in the radius of an announced strike.
+type Risk struct {
+	Vehicle *Vehicle
+	Damage  int
+
+	// Distance is how far the vehicle has to move away from the centre to
+	// leave the radius, Ticks how long that takes at full speed.
+	Distance float64
+	Ticks    int
+}
+
+// IncomingStrike is the opponent's announced nuclear strike.
+type IncomingStrike struct {
+	X, Y      float64
+	SpotterId int64
+
+	// TicksLeft is the number of ticks until the strike lands.
+	TicksLeft int
+
+	// AtRisk lists the own vehicles within the radius, the most damaged
+	// first.
+	AtRisk []*Risk
+
+	// ScaleFactor is the Action_Scale factor centred on the strike that moves
+	// every vehicle at risk out of the radius, 0 if one of them stands right
+	// at the centre. It is capped at 10, the largest factor the server
+	// accepts, so the vehicles within a tenth of the radius of the centre
+	// are still hit, if less.
+	ScaleFactor float64
+}
+
+// Incoming evaluates the nuclear strike announced by the opponent, or returns
+// nil if there is none.
+func (p *NuclearPlanner) Incoming(w *World) *IncomingStrike {
+	op := w.OpponentPlayer()
+	if op == nil || op.NextNuclearStrikeTickIndex < 0 {
+		return nil
+	}
+
+	s := &IncomingStrike{
+		X:         op.NextNuclearStrikeX,
+		Y:         op.NextNuclearStrikeY,
+		SpotterId: op.NextNuclearStrikeVehicleId,
+		TicksLeft: op.NextNuclearStrikeTickIndex - w.TickIndex,
+	}
+
+	r := p.game.TacticalNuclearStrikeRadius
+	closest := math.Inf(1)
+
+	for _, v := range w.Vehicles.Mine() {
+		dmg := p.Damage(s.X, s.Y, v)
+		if dmg == 0 {
+			continue
+		}
+
+		d := v.GetDistanceTo(s.X, s.Y)
+		closest = math.Min(closest, d)
+
+		speed := v.MaxSpeed
+		if w.Map != nil {
+			_, _, factor := w.Map.VehicleFactors(v)
+			speed *= factor
+		}
+		ticks := math.MaxInt32
+		if speed > 0 {
+			ticks = int(math.Ceil((r - d) / speed))
+		}
+
+		s.AtRisk = append(s.AtRisk, &Risk{Vehicle: v, Damage: dmg, Distance: r - d, Ticks: ticks})
+	}
+
+	sort.SliceStable(s.AtRisk, func(i, j int) bool { return s.AtRisk[i].Damage > s.AtRisk[j].Damage })
+
+	if len(s.AtRisk) > 0 && closest > 0 {
+		s.ScaleFactor = math.Min(r/closest, maxScaleFactor)
+	}
+
+	return s
+}
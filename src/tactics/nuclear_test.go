@@ -0,0 +1,89 @@
+package tactics
+
+import (
+	. "model"
+	"testing"
+)
+
+func TestIncomingScaleFactor(t *testing.T) {
+	g := DefaultGame(1)
+	r := g.TacticalNuclearStrikeRadius
+
+	tests := []struct {
+		name     string
+		distance float64
+		want     float64
+	}{
+		{"half the radius", r / 2, 2},
+		{"a tenth of the radius", r / 10, 10},
+		{"close to the centre", r / 50, 10},
+		{"at the centre", 0, 0},
+	}
+
+	for _, tt := range tests {
+		v := g.NewVehicle(Vehicle_Tank)
+		v.Id, v.PlayerId = 1, 1
+		v.X, v.Y = 500+tt.distance, 500
+
+		w := &World{
+			TickIndex: 10,
+			Players: []*Player{
+				{Id: 1, Me: true, NextNuclearStrikeTickIndex: -1},
+				{Id: 2, NextNuclearStrikeTickIndex: 40, NextNuclearStrikeX: 500, NextNuclearStrikeY: 500, NextNuclearStrikeVehicleId: 7},
+			},
+			NewVehicles: []*Vehicle{v},
+			Vehicles:    NewVehicleRegistry(),
+		}
+		w.Vehicles.Update(w)
+
+		s := NewNuclearPlanner(g).Incoming(w)
+		if s == nil || len(s.AtRisk) != 1 {
+			t.Fatalf("%s: Incoming = %+v, want one vehicle at risk", tt.name, s)
+		}
+		if s.ScaleFactor != tt.want {
+			t.Errorf("%s: ScaleFactor = %v, want %v", tt.name, s.ScaleFactor, tt.want)
+		}
+		if s.ScaleFactor != 0 && (s.ScaleFactor < 0.1 || s.ScaleFactor > 10) {
+			t.Errorf("%s: ScaleFactor %v out of the Action_Scale range", tt.name, s.ScaleFactor)
+		}
+	}
+}
+
+func TestPlanSpotterOutsideBlast(t *testing.T) {
+	g := DefaultGame(1)
+
+	vehicle := func(id, player int64, vt VehicleType, x, y float64) *Vehicle {
+		v := g.NewVehicle(vt)
+		v.Id, v.PlayerId, v.X, v.Y = id, player, x, y
+		return v
+	}
+	plan := func(vehicles ...*Vehicle) *StrikePlan {
+		w := &World{
+			Width: g.WorldWidth, Height: g.WorldHeight,
+			Players: []*Player{
+				{Id: 1, Me: true, NextNuclearStrikeTickIndex: -1},
+				{Id: 2, NextNuclearStrikeTickIndex: -1},
+			},
+			NewVehicles: vehicles,
+			Vehicles:    NewVehicleRegistry(),
+		}
+		w.Vehicles.Update(w)
+		return NewNuclearPlanner(g).Plan(w, w.MyPlayer())
+	}
+
+	var enemies []*Vehicle
+	for i := 0; i < 5; i++ {
+		enemies = append(enemies, vehicle(int64(10+i), 2, Vehicle_Tank, 496+float64(2*i), 500))
+	}
+	// The tank sees the enemies from within the blast radius, the fighter
+	// from outside it.
+	tank := vehicle(1, 1, Vehicle_Tank, 530, 500)
+	fighter := vehicle(2, 1, Vehicle_Fighter, 500, 600)
+
+	if p := plan(append([]*Vehicle{tank, fighter}, enemies...)...); p == nil || p.SpotterId != fighter.Id {
+		t.Errorf("spotters inside and outside the radius: %+v, want the fighter to spot", p)
+	}
+	if p := plan(append([]*Vehicle{tank}, enemies...)...); p != nil {
+		t.Errorf("spotter inside the radius only: %+v, want no strike", p)
+	}
+}
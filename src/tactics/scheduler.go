@@ -0,0 +1,249 @@
+// Package tactics holds the building blocks strategies use to plan and issue
+// their moves on top of the raw model types.
+package tactics
+
+import (
+	"errors"
+	. "model"
+	"sort"
+)
+
+var (
+	ErrCancelled = errors.New("tactics: order cancelled")
+	ErrTimeout   = errors.New("tactics: order timed out")
+)
+
+type OrderStatus int
+
+const (
+	Order_Pending OrderStatus = iota
+	Order_Running
+	Order_Done
+	Order_Failed
+	Order_Cancelled
+)
+
+var orderStatusNames = []string{"pending", "running", "done", "failed", "cancelled"}
+
+func (s OrderStatus) String() string {
+	if s >= 0 && int(s) < len(orderStatusNames) {
+		return orderStatusNames[s]
+	}
+	return "OrderStatus(?)"
+}
+
+// Step is a single action of an order.
+type Step struct {
+	// Ready, if set, holds the step back until it returns true, e.g. until a
+	// group is selected.
+	Ready func(w *World) bool
+
+	// Build fills the move of the tick. The move comes with the usual
+	// defaults. An error fails the whole order and leaves the move as it was.
+	Build func(w *World, m *Move) error
+}
+
+// Order is a sequence of steps performed on consecutive available ticks.
+// Once an order has performed its first step it keeps the scheduler to itself
+// until it is finished, so that no other order changes the selection between
+// its steps.
+type Order struct {
+	Steps []Step
+
+	// Orders with a higher priority start first, equal priorities start in
+	// the order they were pushed.
+	Priority int
+
+	// Timeout is the number of ticks after which an unfinished order fails
+	// with ErrTimeout, 0 for none.
+	Timeout int
+
+	// OnDone, if set, is called once the order is done, failed or cancelled.
+	OnDone func(o *Order)
+
+	seq    int
+	pushed int
+	next   int
+	status OrderStatus
+	err    error
+}
+
+func (o *Order) Status() OrderStatus {
+	return o.status
+}
+
+// Err returns why the order failed or was cancelled.
+func (o *Order) Err() error {
+	return o.err
+}
+
+func (o *Order) finished() bool {
+	return o.status >= Order_Done
+}
+
+func (o *Order) finish(status OrderStatus, err error) {
+	o.status, o.err = status, err
+	if o.OnDone != nil {
+		o.OnDone(o)
+	}
+}
+
+// Scheduler turns queued orders into one move per tick, spending actions only
+// when the action budget allows.
+type Scheduler struct {
+	budget *ActionBudget
+	orders []*Order
+	active *Order
+	seq    int
+	tick   int
+}
+
+// NewScheduler creates a scheduler spending the actions of budget. The budget
+// is shared with the rest of the strategy and must be kept up to date every
+// tick, typically by the CountActions middleware of the client, so that it
+// also counts the moves made without the scheduler.
+func NewScheduler(budget *ActionBudget) *Scheduler {
+	return &Scheduler{budget: budget}
+}
+
+// Push queues the order and returns it.
+func (s *Scheduler) Push(o *Order) *Order {
+	s.seq++
+	o.seq, o.pushed, o.next = s.seq, s.tick, 0
+	o.status, o.err = Order_Pending, nil
+
+	if len(o.Steps) == 0 {
+		o.finish(Order_Done, nil)
+		return o
+	}
+
+	s.orders = append(s.orders, o)
+	return o
+}
+
+// Cancel removes the order from the queue. Steps already performed are not
+// undone.
+func (s *Scheduler) Cancel(o *Order) {
+	if !o.finished() && s.remove(o) {
+		o.finish(Order_Cancelled, ErrCancelled)
+	}
+}
+
+// CancelAll cancels every queued order.
+func (s *Scheduler) CancelAll() {
+	for len(s.orders) > 0 {
+		s.Cancel(s.orders[0])
+	}
+}
+
+// Len returns the number of queued orders.
+func (s *Scheduler) Len() int {
+	return len(s.orders)
+}
+
+func (s *Scheduler) remove(o *Order) bool {
+	for i, q := range s.orders {
+		if q == o {
+			s.orders = append(s.orders[:i], s.orders[i+1:]...)
+			if s.active == o {
+				s.active = nil
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Move fills m with the next step due on this tick, if any. It must be called
+// once per tick with the move the strategy is about to send; m is left alone
+// when there is nothing to do or no action is available.
+func (s *Scheduler) Move(w *World, m *Move) {
+	s.tick = w.TickIndex
+
+	for _, o := range append([]*Order(nil), s.orders...) {
+		if o.Timeout > 0 && s.tick-o.pushed >= o.Timeout {
+			s.remove(o)
+			o.finish(Order_Failed, ErrTimeout)
+		}
+	}
+
+	if !s.budget.Available() {
+		return
+	}
+
+	o := s.pick(w)
+	if o == nil {
+		return
+	}
+
+	if step := &o.Steps[o.next]; step.Build != nil {
+		c := *m
+		if err := step.Build(w, &c); err != nil {
+			s.remove(o)
+			o.finish(Order_Failed, err)
+			return
+		}
+		*m = c
+	}
+
+	o.next++
+	o.status = Order_Running
+	s.active = o
+
+	if o.next == len(o.Steps) {
+		s.remove(o)
+		o.finish(Order_Done, nil)
+	}
+}
+
+// pick returns the order whose step goes next: the active order if there is
+// one, otherwise the pending order with the highest priority whose first step
+// is ready.
+func (s *Scheduler) pick(w *World) *Order {
+	if o := s.active; o != nil {
+		if ready(&o.Steps[o.next], w) {
+			return o
+		}
+		return nil
+	}
+
+	sort.SliceStable(s.orders, func(i, j int) bool {
+		if s.orders[i].Priority != s.orders[j].Priority {
+			return s.orders[i].Priority > s.orders[j].Priority
+		}
+		return s.orders[i].seq < s.orders[j].seq
+	})
+
+	for _, o := range s.orders {
+		if ready(&o.Steps[o.next], w) {
+			return o
+		}
+	}
+	return nil
+}
+
+func ready(step *Step, w *World) bool {
+	return step.Ready == nil || step.Ready(w)
+}
+
+// GroupSelected is a precondition met when every own vehicle of the group is
+// selected and no other own vehicle is.
+func GroupSelected(group int) func(w *World) bool {
+	return func(w *World) bool {
+		found := false
+		for _, v := range w.Vehicles.Mine() {
+			if v.InGroup(group) != v.Selected {
+				return false
+			}
+			found = found || v.Selected
+		}
+		return found
+	}
+}
+
+// AfterTick is a precondition met from the given tick on.
+func AfterTick(tick int) func(w *World) bool {
+	return func(w *World) bool {
+		return w.TickIndex >= tick
+	}
+}
@@ -0,0 +1,187 @@
+package tactics
+
+import (
+	"errors"
+	. "model"
+	"testing"
+)
+
+// moveStep builds Action_Move with X set to tag, to tell the steps apart.
+func moveStep(tag float64) Step {
+	return Step{Build: func(w *World, m *Move) error {
+		m.Action, m.X = Action_Move, tag
+		return nil
+	}}
+}
+
+func moveOrder(priority int, tags ...float64) *Order {
+	o := &Order{Priority: priority}
+	for _, tag := range tags {
+		o.Steps = append(o.Steps, moveStep(tag))
+	}
+	return o
+}
+
+// schedule plays one tick like the client does with CountActions around the
+// strategy and returns the tag of the step sent, 0 for none.
+func schedule(s *Scheduler, b *ActionBudget, tick, cooldown int) float64 {
+	w := &World{TickIndex: tick, Players: []*Player{{Id: 1, Me: true, RemainingActionCooldownTicks: cooldown}}}
+	b.Update(w)
+	m := NewMove()
+	s.Move(w, m)
+	b.Record(m)
+	if m.Action == Action_None {
+		return 0
+	}
+	return m.X
+}
+
+func TestSchedulerQueue(t *testing.T) {
+	b := NewActionBudget(DefaultGame(1))
+	s := NewScheduler(b)
+
+	var done []float64
+	onDone := func(o *Order) { done = append(done, stepTag(o.Steps[0])) }
+
+	low := s.Push(moveOrder(0, 1, 2))
+	high := s.Push(moveOrder(1, 10))
+	last := s.Push(moveOrder(0, 3))
+	for _, o := range []*Order{low, high, last} {
+		o.OnDone = onDone
+	}
+
+	var sent []float64
+	for tick := 0; tick < 5; tick++ {
+		sent = append(sent, schedule(s, b, tick, 0))
+	}
+
+	if want := []float64{10, 1, 2, 3, 0}; !equalTags(sent, want) {
+		t.Errorf("sent %v, want %v", sent, want)
+	}
+	if want := []float64{10, 1, 3}; !equalTags(done, want) {
+		t.Errorf("orders done %v, want %v", done, want)
+	}
+	if s.Len() != 0 || low.Status() != Order_Done {
+		t.Errorf("%d orders left, the first one %v", s.Len(), low.Status())
+	}
+}
+
+func TestSchedulerReady(t *testing.T) {
+	b := NewActionBudget(DefaultGame(1))
+	s := NewScheduler(b)
+
+	gate := false
+	held := moveOrder(1, 1)
+	held.Steps[0].Ready = func(*World) bool { return gate }
+	s.Push(held)
+
+	twoSteps := moveOrder(0, 2, 3)
+	twoSteps.Steps[1].Ready = func(*World) bool { return gate }
+	s.Push(twoSteps)
+	s.Push(moveOrder(0, 4))
+
+	var sent []float64
+	for tick := 0; tick < 3; tick++ {
+		sent = append(sent, schedule(s, b, tick, 0))
+	}
+	// The order that started keeps the scheduler until its next step is
+	// ready, so neither the held order nor the last one cut in.
+	if want := []float64{2, 0, 0}; !equalTags(sent, want) {
+		t.Fatalf("sent %v, want %v", sent, want)
+	}
+	if twoSteps.Status() != Order_Running || held.Status() != Order_Pending {
+		t.Errorf("orders %v and %v, want running and pending", twoSteps.Status(), held.Status())
+	}
+
+	gate = true
+	sent = nil
+	for tick := 3; tick < 6; tick++ {
+		sent = append(sent, schedule(s, b, tick, 0))
+	}
+	if want := []float64{3, 1, 4}; !equalTags(sent, want) {
+		t.Errorf("sent %v, want %v", sent, want)
+	}
+}
+
+func TestSchedulerBudget(t *testing.T) {
+	g := DefaultGame(1)
+	b := NewActionBudget(g)
+	s := NewScheduler(b)
+
+	tags := make([]float64, g.BaseActionCount+1)
+	for i := range tags {
+		tags[i] = float64(i + 1)
+	}
+	o := s.Push(moveOrder(0, tags...))
+
+	// The server's cooldown holds the order back.
+	if tag := schedule(s, b, 0, 5); tag != 0 || o.Status() != Order_Pending {
+		t.Fatalf("sent step %v during the action cooldown", tag)
+	}
+
+	for tick := 1; tick <= g.BaseActionCount; tick++ {
+		if tag := schedule(s, b, tick, 0); tag != float64(tick) {
+			t.Fatalf("tick %d: sent step %v, want %d", tick, tag, tick)
+		}
+	}
+
+	// The budget is empty until the first action leaves the window.
+	first := 1 + g.ActionDetectionInterval
+	for tick := g.BaseActionCount + 1; tick < first; tick++ {
+		if tag := schedule(s, b, tick, 0); tag != 0 {
+			t.Fatalf("tick %d: sent step %v with an empty budget", tick, tag)
+		}
+	}
+	if tag := schedule(s, b, first, 0); tag != tags[len(tags)-1] || o.Status() != Order_Done {
+		t.Errorf("tick %d: sent step %v, order %v", first, tag, o.Status())
+	}
+}
+
+func TestSchedulerFailures(t *testing.T) {
+	b := NewActionBudget(DefaultGame(1))
+	s := NewScheduler(b)
+
+	errBuild := errors.New("no target")
+	broken := s.Push(&Order{Priority: 1, Steps: []Step{{Build: func(*World, *Move) error { return errBuild }}}})
+	late := s.Push(&Order{Timeout: 2, Steps: []Step{{Ready: func(*World) bool { return false }}}})
+	cancelled := s.Push(moveOrder(0, 1))
+	s.Cancel(cancelled)
+
+	for tick := 0; tick < 3; tick++ {
+		if tag := schedule(s, b, tick, 0); tag != 0 {
+			t.Errorf("tick %d: sent step %v", tick, tag)
+		}
+	}
+
+	if broken.Status() != Order_Failed || broken.Err() != errBuild {
+		t.Errorf("broken order %v with %v", broken.Status(), broken.Err())
+	}
+	if late.Status() != Order_Failed || late.Err() != ErrTimeout {
+		t.Errorf("late order %v with %v", late.Status(), late.Err())
+	}
+	if cancelled.Status() != Order_Cancelled || cancelled.Err() != ErrCancelled {
+		t.Errorf("cancelled order %v with %v", cancelled.Status(), cancelled.Err())
+	}
+	if s.Len() != 0 || b.Used() != 0 {
+		t.Errorf("%d orders left, %d actions used", s.Len(), b.Used())
+	}
+}
+
+// stepTag returns the tag a moveStep builds.
+func stepTag(step Step) float64 {
+	m := NewMove()
+	step.Build(nil, m)
+	return m.X
+}
+
+func equalTags(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
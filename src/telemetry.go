@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	. "model"
+	"time"
+)
+
+// TickLogger writes one JSON line per tick: the scores, the vehicle counts
+// per owner and type, the move sent, the actions left and the nuclear strike
+// events, together with the fields the strategy attached with Set or
+// LogField. Install it with LogTicks.
+type TickLogger struct {
+	enc *json.Encoder
+	err error
+
+	budget *ActionBudget
+	fields map[string]interface{}
+
+	// strikes holds every player as of the previous tick, to tell when a
+	// strike is announced or lands.
+	strikes map[int64]*Player
+}
+
+func NewTickLogger(w io.Writer) *TickLogger {
+	return &TickLogger{enc: json.NewEncoder(w), strikes: make(map[int64]*Player)}
+}
+
+// Set attaches a field to the line of the current tick. It does nothing on a
+// nil logger, so strategies can call it whether logging is enabled or not.
+func (l *TickLogger) Set(key string, value interface{}) {
+	if l == nil {
+		return
+	}
+	if l.fields == nil {
+		l.fields = make(map[string]interface{})
+	}
+	l.fields[key] = value
+}
+
+// Err returns the first error writing a line. No line is written after it.
+// A nil logger has no error.
+func (l *TickLogger) Err() error {
+	if l == nil {
+		return nil
+	}
+	return l.err
+}
+
+type tickLoggerKey struct{}
+
+// LogField attaches a field to the line of the current tick if the strategy
+// runs under LogTicks, and does nothing otherwise.
+func LogField(ctx context.Context, key string, value interface{}) {
+	if l, ok := ctx.Value(tickLoggerKey{}).(*TickLogger); ok {
+		l.Set(key, value)
+	}
+}
+
+// LogTicks writes the line of every tick with l once the strategy has moved.
+// Put it first in the chain, right before Recover, to log the move as it is
+// sent: a panic caught by Recover then still gets its line, with Action_None
+// as the move.
+func LogTicks(l *TickLogger) Middleware {
+	return func(next Strategy) Strategy {
+		return ContextStrategyFunc(func(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+			if l.budget == nil {
+				l.budget = NewActionBudget(g)
+			}
+			l.budget.Update(w)
+
+			start := time.Now()
+			moveContext(context.WithValue(ctx, tickLoggerKey{}, l), next, p, w, g, m)
+			took := time.Since(start)
+
+			l.budget.Record(m)
+			l.write(w, m, took)
+		})
+	}
+}
+
+type tickLine struct {
+	Tick        int                       `json:"tick"`
+	TookMs      float64                   `json:"took_ms"`
+	Players     []tickPlayer              `json:"players"`
+	Vehicles    map[string]map[string]int `json:"vehicles,omitempty"`
+	Move        map[string]interface{}    `json:"move,omitempty"`
+	ActionsLeft int                       `json:"actions_left"`
+	Nuclear     []nuclearEvent            `json:"nuclear,omitempty"`
+	Fields      map[string]interface{}    `json:"fields,omitempty"`
+}
+
+type tickPlayer struct {
+	Id                int64 `json:"id"`
+	Me                bool  `json:"me"`
+	Score             int   `json:"score"`
+	Crashed           bool  `json:"crashed,omitempty"`
+	ActionCooldown    int   `json:"action_cooldown"`
+	NuclearCooldown   int   `json:"nuclear_cooldown"`
+	NextNuclearStrike int   `json:"next_nuclear_strike"`
+}
+
+// nuclearEvent is a strike announced, landed or called off because its
+// spotter was lost, on the tick of the line.
+type nuclearEvent struct {
+	Event     string  `json:"event"`
+	Player    int64   `json:"player"`
+	VehicleId int64   `json:"vehicle"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Tick      int     `json:"tick"`
+}
+
+func (l *TickLogger) write(w *World, m *Move, took time.Duration) {
+	line := &tickLine{
+		Tick:        w.TickIndex,
+		TookMs:      float64(took) / float64(time.Millisecond),
+		Move:        moveFields(m),
+		ActionsLeft: l.budget.Remaining(),
+		Nuclear:     l.nuclearEvents(w),
+		Fields:      l.fields,
+	}
+	l.fields = nil
+
+	for _, p := range w.Players {
+		line.Players = append(line.Players, tickPlayer{
+			Id:                p.Id,
+			Me:                p.Me,
+			Score:             p.Score,
+			Crashed:           p.StrategyCrashed,
+			ActionCooldown:    p.RemainingActionCooldownTicks,
+			NuclearCooldown:   p.RemainingNuclearStrikeCooldownTicks,
+			NextNuclearStrike: p.NextNuclearStrikeTickIndex,
+		})
+	}
+
+	if w.Vehicles != nil {
+		line.Vehicles = map[string]map[string]int{"mine": {}, "enemy": {}}
+		for _, v := range w.Vehicles.Mine() {
+			line.Vehicles["mine"][v.Type.String()]++
+		}
+		for _, v := range w.Vehicles.Enemy() {
+			line.Vehicles["enemy"][v.Type.String()]++
+		}
+	}
+
+	if l.err == nil {
+		l.err = l.enc.Encode(line)
+	}
+}
+
+func (l *TickLogger) nuclearEvents(w *World) []nuclearEvent {
+	var events []nuclearEvent
+	for _, p := range w.Players {
+		prev := l.strikes[p.Id]
+		switch {
+		case p.NextNuclearStrikeTickIndex >= 0 && (prev == nil || prev.NextNuclearStrikeTickIndex < 0):
+			events = append(events, nuclearEvent{"announced", p.Id, p.NextNuclearStrikeVehicleId,
+				p.NextNuclearStrikeX, p.NextNuclearStrikeY, p.NextNuclearStrikeTickIndex})
+		case p.NextNuclearStrikeTickIndex < 0 && prev != nil && prev.NextNuclearStrikeTickIndex >= 0:
+			event := "landed"
+			if prev.NextNuclearStrikeTickIndex > w.TickIndex {
+				event = "cancelled"
+			}
+			events = append(events, nuclearEvent{event, p.Id, prev.NextNuclearStrikeVehicleId,
+				prev.NextNuclearStrikeX, prev.NextNuclearStrikeY, prev.NextNuclearStrikeTickIndex})
+		}
+
+		c := *p
+		l.strikes[p.Id] = &c
+	}
+	return events
+}
+
+// moveFields returns the parameters of the move that matter for its action,
+// nil for Action_None.
+func moveFields(m *Move) map[string]interface{} {
+	f := map[string]interface{}{"action": m.Action.String()}
+
+	switch m.Action {
+	case Action_None:
+		return nil
+	case Action_ClearAndSelect, Action_AddToSelection, Action_Deselect:
+		if m.Group != 0 {
+			f["group"] = m.Group
+			break
+		}
+		f["left"], f["top"], f["right"], f["bottom"] = m.Left, m.Top, m.Right, m.Bottom
+		if m.Type != Vehicle_None {
+			f["type"] = m.Type.String()
+		}
+	case Action_Assign, Action_Dismiss, Action_Disband:
+		f["group"] = m.Group
+	case Action_Move:
+		f["x"], f["y"], f["max_speed"] = m.X, m.Y, m.MaxSpeed
+	case Action_Rotate:
+		f["x"], f["y"], f["angle"] = m.X, m.Y, m.Angle
+		f["max_speed"], f["max_angular_speed"] = m.MaxSpeed, m.MaxAngularSpeed
+	case Action_Scale:
+		f["x"], f["y"], f["factor"], f["max_speed"] = m.X, m.Y, m.Factor, m.MaxSpeed
+	case Action_SetupVehicleProduction:
+		f["facility"], f["type"] = m.FacilityId, m.Type.String()
+	case Action_TacticalNuclearStrike:
+		f["vehicle"], f["x"], f["y"] = m.VehicleId, m.X, m.Y
+	}
+	return f
+}
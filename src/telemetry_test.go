@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	. "model"
+	"os"
+	"path/filepath"
+	"server"
+	"strings"
+	"testing"
+)
+
+func TestPlayLogsPanickingTicks(t *testing.T) {
+	g := DefaultGame(1)
+	g.TickCount = 3
+	simulation := server.Simulate(g, StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {}))
+	cfg, done := serve(t, simulation)
+	cfg.TelemetryFile = filepath.Join(t.TempDir(), "telemetry.jsonl")
+
+	s := StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {
+		*m = *SelectRect(0, 0, w.Width, w.Height)
+		if w.TickIndex == 1 {
+			panic("boom")
+		}
+	})
+
+	if err := Play(cfg, s); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	f, err := os.Open(cfg.TelemetryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines []map[string]interface{}
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var line map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("line %d: %v", len(lines), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != g.TickCount {
+		t.Fatalf("%d lines, want %d", len(lines), g.TickCount)
+	}
+
+	for tick, line := range lines {
+		fields, _ := line["fields"].(map[string]interface{})
+		panicked, _ := fields["panic"].(string)
+		_, moved := line["move"]
+
+		if tick == 1 {
+			if !strings.Contains(panicked, "boom") {
+				t.Errorf("tick 1: panic field %q, want the panic", panicked)
+			}
+			if moved {
+				t.Errorf("tick 1: move %v logged, want none", line["move"])
+			}
+			continue
+		}
+		if panicked != "" || !moved {
+			t.Errorf("tick %d: panic %q, move %v, want a move and no panic", tick, panicked, line["move"])
+		}
+	}
+}
+
+func TestNilTickLogger(t *testing.T) {
+	var l *TickLogger
+	l.Set("key", 1)
+	if err := l.Err(); err != nil {
+		t.Errorf("Err() = %v on a nil logger", err)
+	}
+	LogField(context.Background(), "key", 1)
+}
+
+func TestPlayRecoversWithoutTelemetry(t *testing.T) {
+	g := DefaultGame(1)
+	g.TickCount = 3
+	simulation := server.Simulate(g, StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {}))
+	cfg, done := serve(t, simulation)
+
+	ticks := 0
+	s := StrategyFunc(func(p *Player, w *World, g *Game, m *Move) {
+		ticks++
+		if w.TickIndex == 1 {
+			panic("boom")
+		}
+	})
+
+	if err := Play(cfg, s); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	if ticks != g.TickCount {
+		t.Errorf("strategy called on %d ticks, want %d", ticks, g.TickCount)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	. "model"
+	"time"
+)
+
+// ContextStrategy is a strategy that can be told how long it may take. The
+// context passed to MoveContext expires when the time of the tick is up, so
+// that expensive planners can stop early and use their best answer so far.
+type ContextStrategy interface {
+	Strategy
+	MoveContext(ctx context.Context, p *Player, w *World, g *Game, m *Move)
+}
+
+// ContextStrategyFunc lets an ordinary function be used as a ContextStrategy.
+// Move calls it with a context that never expires.
+type ContextStrategyFunc func(ctx context.Context, p *Player, w *World, g *Game, m *Move)
+
+func (f ContextStrategyFunc) Move(p *Player, w *World, g *Game, m *Move) {
+	f(context.Background(), p, w, g, m)
+}
+
+func (f ContextStrategyFunc) MoveContext(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+	f(ctx, p, w, g, m)
+}
+
+// moveContext calls MoveContext if s implements it and Move otherwise.
+func moveContext(ctx context.Context, s Strategy, p *Player, w *World, g *Game, m *Move) {
+	if cs, ok := s.(ContextStrategy); ok {
+		cs.MoveContext(ctx, p, w, g, m)
+		return
+	}
+	s.Move(p, w, g, m)
+}
+
+// TimeBudget shares the time limit of a game among its ticks. Every tick may
+// take Burst times its fair share of the time left, the time left divided by
+// the ticks left; a tick taking longer is an overrun.
+type TimeBudget struct {
+	// Total is the time limit of the game, 0 for none.
+	Total time.Duration
+	Burst float64
+
+	ticks  int
+	report TimeReport
+}
+
+// TimeReport sums up the time the strategy took.
+type TimeReport struct {
+	Ticks   int
+	Used    time.Duration
+	Limit   time.Duration
+	Slowest time.Duration
+
+	// SlowestTick is the index of the slowest tick.
+	SlowestTick int
+
+	// Overruns counts the ticks that took longer than they were allowed,
+	// Overtime sums the time they took in excess.
+	Overruns int
+	Overtime time.Duration
+}
+
+func (r *TimeReport) String() string {
+	limit := "no limit"
+	if r.Limit > 0 {
+		limit = fmt.Sprintf("limit %v", r.Limit)
+	}
+	return fmt.Sprintf("time used %v (%s) over %d ticks, slowest tick %d took %v, %d overruns by %v in total",
+		r.Used, limit, r.Ticks, r.SlowestTick, r.Slowest, r.Overruns, r.Overtime)
+}
+
+// NewTimeBudget creates the budget of a game of the given number of ticks.
+func NewTimeBudget(total time.Duration, ticks int) *TimeBudget {
+	return &TimeBudget{Total: total, Burst: 2, ticks: ticks, report: TimeReport{Limit: total}}
+}
+
+// Allowance returns the time the tick may take, 0 without a limit.
+func (b *TimeBudget) Allowance(tick int) time.Duration {
+	if b.Total <= 0 {
+		return 0
+	}
+
+	left := b.Total - b.report.Used
+	if left <= 0 {
+		return time.Nanosecond
+	}
+	ticksLeft := b.ticks - tick
+	if ticksLeft < 1 {
+		ticksLeft = 1
+	}
+
+	allowance := time.Duration(float64(left) / float64(ticksLeft) * b.Burst)
+	if allowance > left {
+		allowance = left
+	}
+	return allowance
+}
+
+// Move calls s with a context expiring after the allowance of the tick and
+// accounts for the time it took.
+func (b *TimeBudget) Move(s Strategy, p *Player, w *World, g *Game, m *Move) {
+	ctx := context.Background()
+	allowance := b.Allowance(w.TickIndex)
+	if allowance > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, allowance)
+		defer cancel()
+	}
+
+	start := time.Now()
+	moveContext(ctx, s, p, w, g, m)
+	b.record(w.TickIndex, time.Since(start), allowance)
+}
+
+func (b *TimeBudget) record(tick int, took, allowance time.Duration) {
+	r := &b.report
+	r.Ticks++
+	r.Used += took
+	if took > r.Slowest {
+		r.Slowest, r.SlowestTick = took, tick
+	}
+	if allowance > 0 && took > allowance {
+		r.Overruns++
+		r.Overtime += took - allowance
+	}
+}
+
+// Report returns the summary of the ticks so far.
+func (b *TimeBudget) Report() *TimeReport {
+	r := b.report
+	return &r
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	. "model"
+	"server"
+	"testing"
+	"time"
+)
+
+func TestTimeBudgetNoLimit(t *testing.T) {
+	b := NewTimeBudget(0, 10)
+	if a := b.Allowance(0); a != 0 {
+		t.Errorf("Allowance(0) = %v without a limit, want 0", a)
+	}
+
+	b.record(0, time.Second, b.Allowance(0))
+	r := b.Report()
+	if r.Ticks != 1 || r.Used != time.Second || r.Overruns != 0 || r.Limit != 0 {
+		t.Errorf("report %+v, want one tick of 1s and no overrun", r)
+	}
+	if a := b.Allowance(1); a != 0 {
+		t.Errorf("Allowance(1) = %v without a limit, want 0", a)
+	}
+}
+
+func TestTimeBudgetAllowance(t *testing.T) {
+	ms := time.Millisecond
+	b := NewTimeBudget(100*ms, 10)
+
+	// Burst times the fair share of the time left.
+	if a, want := b.Allowance(0), 20*ms; a != want {
+		t.Errorf("Allowance(0) = %v, want %v", a, want)
+	}
+
+	// An overrun counts the time in excess.
+	b.record(0, 30*ms, b.Allowance(0))
+	if a, want := b.Allowance(1), time.Duration(float64(70*ms)/9*2); a != want {
+		t.Errorf("Allowance(1) = %v, want %v", a, want)
+	}
+	b.record(1, 5*ms, b.Allowance(1))
+
+	// The last tick may take all that is left but no more.
+	if a, want := b.Allowance(9), 65*ms; a != want {
+		t.Errorf("Allowance(9) = %v, want %v", a, want)
+	}
+
+	r := b.Report()
+	want := TimeReport{Ticks: 2, Used: 35 * ms, Limit: 100 * ms, Slowest: 30 * ms, SlowestTick: 0, Overruns: 1, Overtime: 10 * ms}
+	if *r != want {
+		t.Errorf("report %+v, want %+v", *r, want)
+	}
+}
+
+func TestTimeBudgetExhausted(t *testing.T) {
+	b := NewTimeBudget(10*time.Millisecond, 10)
+	b.record(0, 12*time.Millisecond, b.Allowance(0))
+
+	if a := b.Allowance(1); a != time.Nanosecond {
+		t.Fatalf("Allowance(1) = %v once exhausted, want 1ns", a)
+	}
+
+	// The strategy still moves, with a context that has already expired.
+	expired := false
+	b.Move(ContextStrategyFunc(func(ctx context.Context, p *Player, w *World, g *Game, m *Move) {
+		<-ctx.Done()
+		expired = ctx.Err() == context.DeadlineExceeded
+		m.Action = Action_Move
+	}), new(Player), &World{TickIndex: 1}, DefaultGame(1), NewMove())
+
+	r := b.Report()
+	if !expired || r.Ticks != 2 || r.Overruns != 2 || r.SlowestTick != 0 {
+		t.Errorf("expired %v, report %+v", expired, r)
+	}
+}
+
+func TestRunReportsTimeAtGameOver(t *testing.T) {
+	g := DefaultGame(1)
+	var contexts []*PlayerContext
+	for tick := 0; tick < 3; tick++ {
+		p := &Player{Id: 1, Me: true, NextNuclearStrikeVehicleId: -1, NextNuclearStrikeTickIndex: -1}
+		w := &World{TickIndex: tick, TickCount: 3, Players: []*Player{p}}
+		contexts = append(contexts, &PlayerContext{Player: p, World: w})
+	}
+	cfg, done := serve(t, server.NewScript(g, contexts...))
+
+	cli := NewRemoteProcessClient()
+	defer cli.Close()
+	cli.LimitTime(time.Minute)
+
+	var reports []*TimeReport
+	cli.ReportTime(func(r *TimeReport) { reports = append(reports, r) })
+
+	if err := cli.DialTimeout(cfg.Host, cfg.Port, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Run(cfg.Token, StrategyFunc(func(*Player, *World, *Game, *Move) {})); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	if len(reports) != 1 || reports[0].Ticks != 3 || reports[0].Limit != time.Minute {
+		t.Errorf("reports %+v, want one over 3 ticks with a limit of 1m", reports)
+	}
+}